@@ -0,0 +1,72 @@
+// Package apperr defines the error kinds shared by repositories, services and handlers.
+package apperr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Error kinds. Domain errors wrap one of these so handlers can map them to a status code.
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrInvalid      = errors.New("invalid input")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	// ErrTooManyRequests means the client must wait before trying again.
+	ErrTooManyRequests = errors.New("too many requests")
+)
+
+// Error is a domain error of a given kind with a client-facing message.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+// New creates an Error of the given kind.
+func New(kind error, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Kind }
+
+// Status returns the HTTP status code for err.
+func Status(err error) int {
+	switch {
+	case errors.Is(err, ErrBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalid):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Message returns a message for err that is safe to show to clients.
+// Details of unexpected errors are hidden.
+func Message(err error) string {
+	switch Status(err) {
+	case http.StatusInternalServerError:
+		return "internal server error"
+	case http.StatusServiceUnavailable:
+		return "service unavailable"
+	default:
+		return err.Error()
+	}
+}
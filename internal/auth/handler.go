@@ -1,23 +1,49 @@
 package auth
 
 import (
-	"fmt"
+	"errors"
+	"log/slog"
+	"math"
 	"net/http"
-	"strings"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+
+	"test-backend/internal/logging"
+	"test-backend/internal/problem"
 	"test-backend/internal/user"
+	"test-backend/internal/validation"
 )
 
 type Handler struct {
-	service user.Service
-	jwtKey  []byte
+	service       user.Service
+	tokens        TokenConfig
+	mail          MailConfig
+	refreshTokens RefreshStore
+	revocations   RevocationStore
+	resets        PasswordResetStore
+	observer      Observer
+	// sending tracks emails being sent in the background.
+	sending sync.WaitGroup
 }
 
-func NewHandler(s user.Service, key []byte) *Handler {
-	return &Handler{service: s, jwtKey: key}
+// NewHandler creates a Handler. observer may be nil.
+func NewHandler(s user.Service, tokens TokenConfig, mailConfig MailConfig, refreshTokens RefreshStore, revocations RevocationStore, resets PasswordResetStore, observer Observer) *Handler {
+	if observer == nil {
+		observer = nopObserver{}
+	}
+	return &Handler{
+		service:       s,
+		tokens:        tokens,
+		mail:          mailConfig,
+		refreshTokens: refreshTokens,
+		revocations:   revocations,
+		resets:        resets,
+		observer:      observer,
+	}
 }
 
 type Credentials struct {
@@ -26,86 +52,250 @@ type Credentials struct {
 	Password string `json:"password"`
 }
 
+// LogValue implements slog.LogValuer so the password is never logged.
+func (cr Credentials) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("email", cr.Email))
+}
+
+// validateRegistration checks credentials submitted to Register against the password policy.
+func (cr Credentials) validateRegistration() error {
+	var v validation.Validator
+	v.MaxLength("name", cr.Name, user.MaxNameLength)
+	v.Email("email", cr.Email)
+	v.Password("password", cr.Password)
+	return v.Err()
+}
+
+// validateLogin checks that credentials submitted to Login are present.
+// The password policy is not applied so accounts created under an older policy can still sign in.
+func (cr Credentials) validateLogin() error {
+	var v validation.Validator
+	v.Required("email", cr.Email)
+	v.Required("password", cr.Password)
+	return v.Err()
+}
+
+// RefreshRequest is the body accepted by Refresh.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 // Register godoc
 // @Summary      Register user
-// @Description  register a new user
+// @Description  register a new user and email them a verification link; when verified email is required no tokens are issued
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        credentials  body      Credentials  true  "Credentials"
-// @Success      201  {object} map[string]string
+// @Success      201  {object} TokenResponse
+// @Success      202
+// @Failure      400  {object}  problem.Problem
+// @Failure      409  {object} problem.Problem
+// @Failure      422  {object} problem.Problem
+// @Failure      429  {object} problem.Problem
 // @Router       /register [post]
 func (h *Handler) Register(c *gin.Context) {
 	var req Credentials
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Error(c, validation.BindError(err))
+		return
+	}
+	if err := req.validateRegistration(); err != nil {
+		problem.Error(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	created, err := h.service.Create(ctx, user.User{Name: req.Name, Email: req.Email, Password: req.Password, Role: user.RoleViewer})
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	// The account exists now, so a failed email is only logged; the user can
+	// ask for another one with ResendVerification.
+	if err := h.sendVerification(ctx, created); err != nil {
+		logging.FromContext(ctx).Error("could not send verification email", "verify_user_id", created.ID, "error", err)
+	}
+	if h.tokens.RequireVerifiedEmail {
+		c.Status(http.StatusAccepted)
 		return
 	}
-	created := h.service.Create(user.User{Name: req.Name, Email: req.Email, Password: req.Password})
-	token, err := h.generateToken(created)
+	tokens, err := h.issueTokens(ctx, created, "")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		problem.Error(c, err)
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"token": token})
+	c.JSON(http.StatusCreated, tokens)
 }
 
 // Login godoc
 // @Summary      Login user
-// @Description  authenticate a user and return JWT
+// @Description  authenticate a user and return JWT, or a challenge token for /login/2fa when two-factor authentication is enabled; repeated failures temporarily lock the account and client IP
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        credentials  body      Credentials  true  "Credentials"
-// @Success      200  {object} map[string]string
-// @Failure      401  {string} string "invalid credentials"
+// @Success      200  {object} TokenResponse
+// @Success      202  {object} ChallengeResponse
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object} problem.Problem
+// @Failure      403  {object} problem.Problem
+// @Failure      422  {object} problem.Problem
+// @Failure      429  {object} problem.Problem
 // @Router       /login [post]
 func (h *Handler) Login(c *gin.Context) {
 	var creds Credentials
 	if err := c.ShouldBindJSON(&creds); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		h.observer.LoginFailed(LoginInvalidRequest)
+		problem.Error(c, validation.BindError(err))
 		return
 	}
-	u, ok := h.service.Authenticate(creds.Email, creds.Password)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+	if err := creds.validateLogin(); err != nil {
+		h.observer.LoginFailed(LoginInvalidRequest)
+		problem.Error(c, err)
 		return
 	}
-	token, err := h.generateToken(u)
+	ctx := c.Request.Context()
+	u, err := h.service.Authenticate(ctx, creds.Email, creds.Password, c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		h.failLogin(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	if err := h.checkVerified(u); err != nil {
+		h.failLogin(c, err)
+		return
+	}
+	enabled, err := h.service.TwoFactorEnabled(ctx, u.ID)
+	if err != nil {
+		h.failLogin(c, err)
+		return
+	}
+	if enabled {
+		challenge, err := h.issueChallenge(u)
+		if err != nil {
+			h.failLogin(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, challenge)
+		return
+	}
+	tokens, err := h.issueTokens(ctx, u, "")
+	if err != nil {
+		h.failLogin(c, err)
+		return
+	}
+	h.observer.LoginSucceeded()
+	c.JSON(http.StatusOK, tokens)
 }
 
-func (h *Handler) generateToken(u user.User) (string, error) {
-	claims := jwt.MapClaims{
-		"sub": u.ID,
-		"exp": time.Now().Add(time.Hour * 72).Unix(),
+// failLogin reports a failed login to the observer and responds with the problem for err.
+func (h *Handler) failLogin(c *gin.Context, err error) {
+	var locked *user.LockedError
+	switch {
+	case errors.As(err, &locked):
+		h.observer.LoginFailed(LoginLocked)
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	case errors.Is(err, user.ErrInvalidCredentials):
+		h.observer.LoginFailed(LoginInvalidCredentials)
+	case errors.Is(err, user.ErrInvalidCode):
+		h.observer.LoginFailed(LoginInvalidCode)
+	case errors.Is(err, ErrInvalidChallenge):
+		h.observer.LoginFailed(LoginInvalidChallenge)
+	case errors.Is(err, ErrEmailNotVerified):
+		h.observer.LoginFailed(LoginUnverified)
+	default:
+		h.observer.LoginFailed(LoginError)
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(h.jwtKey)
+	problem.Error(c, err)
 }
 
-func JWTMiddleware(key []byte) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
-			return
-		}
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method")
-			}
-			return key, nil
-		})
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+// Refresh godoc
+// @Summary      Refresh tokens
+// @Description  exchange a refresh token for a new access and refresh token; each refresh token can be used once
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      RefreshRequest  true  "Refresh token"
+// @Success      200  {object} TokenResponse
+// @Failure      400  {object} problem.Problem
+// @Failure      401  {object} problem.Problem
+// @Failure      403  {object} problem.Problem
+// @Failure      422  {object} problem.Problem
+// @Failure      429  {object} problem.Problem
+// @Router       /token/refresh [post]
+func (h *Handler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Error(c, validation.BindError(err))
+		return
+	}
+	var v validation.Validator
+	v.Required("refresh_token", req.RefreshToken)
+	if err := v.Err(); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	stored, err := h.refreshTokens.Use(ctx, hashOpaqueToken(req.RefreshToken))
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	if stored.Used && !stored.Revoked {
+		// A rotated token was presented again, so it may have been stolen.
+		// Revoke the whole family to cut off whoever holds the latest token.
+		if err := h.refreshTokens.RevokeFamily(ctx, stored.FamilyID); err != nil {
+			problem.Error(c, err)
 			return
 		}
-		c.Next()
+		logging.FromContext(ctx).Warn("refresh token reuse detected, family revoked",
+			"user_id", stored.UserID, "family_id", stored.FamilyID)
+		problem.Error(c, ErrRefreshTokenReused)
+		return
+	}
+	if stored.Revoked || stored.Used || time.Now().After(stored.ExpiresAt) {
+		problem.Error(c, ErrInvalidRefreshToken)
+		return
+	}
+	u, err := h.service.GetByID(ctx, stored.UserID)
+	if errors.Is(err, user.ErrNotFound) {
+		problem.Error(c, ErrInvalidRefreshToken)
+		return
+	}
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	if err := h.checkSession(ctx, u, stored.CreatedAt); err != nil {
+		problem.Error(c, err)
+		return
+	}
+	if err := h.checkVerified(u); err != nil {
+		problem.Error(c, err)
+		return
+	}
+	tokens, err := h.issueTokens(ctx, u, stored.FamilyID)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *Handler) generateToken(u user.User) (string, error) {
+	jti, err := newRandomID()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.Itoa(u.ID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(h.tokens.AccessTokenTTL)),
+		},
+		Role: u.Role,
 	}
+	return h.tokens.Keys.Sign(claims)
 }
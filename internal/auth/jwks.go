@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS godoc
+// @Summary      JSON Web Key Set
+// @Description  public keys that verify access tokens, selected by the token's kid header
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  JWKSet
+// @Router       /.well-known/jwks.json [get]
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.tokens.Keys.JWKS())
+}
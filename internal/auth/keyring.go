@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a key that signs access tokens from ActiveFrom until the next
+// key in its Keyring takes over.
+type SigningKey struct {
+	// ID is sent as the token's kid header.
+	ID         string
+	Method     jwt.SigningMethod
+	ActiveFrom time.Time
+	// sign is the HMAC secret or private key; verify is the matching secret or public key.
+	sign   any
+	verify any
+}
+
+// NewHMACKey returns an HS256 key. HMAC keys are never published in the JWKS.
+func NewHMACKey(id string, secret []byte, activeFrom time.Time) SigningKey {
+	return SigningKey{ID: id, Method: jwt.SigningMethodHS256, ActiveFrom: activeFrom, sign: secret, verify: secret}
+}
+
+// NewAsymmetricKey returns an RS256 key for an RSA private key or an EdDSA key
+// for an Ed25519 private key.
+func NewAsymmetricKey(id string, private crypto.Signer, activeFrom time.Time) (SigningKey, error) {
+	key := SigningKey{ID: id, ActiveFrom: activeFrom, sign: private, verify: private.Public()}
+	switch private.(type) {
+	case *rsa.PrivateKey:
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return SigningKey{}, fmt.Errorf("key %s: unsupported key type %T", id, private)
+	}
+	return key, nil
+}
+
+// LoadAsymmetricKey reads a PEM encoded RSA or Ed25519 private key from path.
+func LoadAsymmetricKey(id, path string, activeFrom time.Time) (SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("key %s: %w", id, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return SigningKey{}, fmt.Errorf("key %s: %s contains no PEM data", id, path)
+	}
+	var private any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("key %s: %w", id, err)
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return SigningKey{}, fmt.Errorf("key %s: unsupported key type %T", id, private)
+	}
+	return NewAsymmetricKey(id, signer, activeFrom)
+}
+
+// Keyring holds the keys used to sign and verify access tokens. At any time the
+// most recently activated key signs new tokens, while the key it replaced keeps
+// verifying tokens for an overlap window so none issued before a rotation are
+// rejected early. Keys whose ActiveFrom lies in the future are already published
+// so verifiers can fetch them before they are used.
+type Keyring struct {
+	keys    []SigningKey
+	overlap time.Duration
+	now     func() time.Time
+}
+
+// NewKeyring returns a Keyring for keys. overlap should be at least the access token lifetime.
+func NewKeyring(overlap time.Duration, keys ...SigningKey) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("keyring needs at least one key")
+	}
+	keys = slices.Clone(keys)
+	slices.SortStableFunc(keys, func(a, b SigningKey) int { return a.ActiveFrom.Compare(b.ActiveFrom) })
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if k.ID == "" {
+			return nil, errors.New("keyring: key id must not be empty")
+		}
+		if seen[k.ID] {
+			return nil, fmt.Errorf("keyring: duplicate key id %q", k.ID)
+		}
+		seen[k.ID] = true
+	}
+	r := &Keyring{keys: keys, overlap: overlap, now: time.Now}
+	if r.current() < 0 {
+		return nil, errors.New("keyring: no key is active yet")
+	}
+	return r, nil
+}
+
+// current returns the index of the key that signs tokens now, or -1 if none is active.
+func (r *Keyring) current() int {
+	now := r.now()
+	i := -1
+	for j, k := range r.keys {
+		if !k.ActiveFrom.After(now) {
+			i = j
+		}
+	}
+	return i
+}
+
+// retired reports whether the key at index i no longer verifies tokens.
+func (r *Keyring) retired(i int) bool {
+	cur := r.current()
+	if i >= cur {
+		return false
+	}
+	// Key i was replaced when key i+1 activated.
+	return r.now().After(r.keys[i+1].ActiveFrom.Add(r.overlap))
+}
+
+// Sign signs claims with the current key and sets its kid header.
+func (r *Keyring) Sign(claims jwt.Claims) (string, error) {
+	i := r.current()
+	if i < 0 {
+		return "", errors.New("keyring: no signing key is active yet")
+	}
+	key := r.keys[i]
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.sign)
+}
+
+// Keyfunc is a jwt.Keyfunc that selects the verification key by the token's kid
+// header and rejects tokens whose algorithm does not match that key.
+func (r *Keyring) Keyfunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	for i, k := range r.keys {
+		if k.ID != kid {
+			continue
+		}
+		if t.Method.Alg() != k.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s for key %s", t.Method.Alg(), kid)
+		}
+		if r.retired(i) {
+			return nil, fmt.Errorf("key %s has been retired", kid)
+		}
+		return k.verify, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// N and E are set for RSA keys.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Curve and X are set for Ed25519 keys.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKSet is a JSON Web Key Set.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that currently verify tokens, plus scheduled keys.
+func (r *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for i, k := range r.keys {
+		if r.retired(i) {
+			continue
+		}
+		jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Method.Alg()}
+		switch pub := k.verify.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
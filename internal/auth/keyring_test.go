@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// keyringAt returns a Keyring for keys whose clock reads *now.
+func keyringAt(t *testing.T, now *time.Time, overlap time.Duration, keys ...SigningKey) *Keyring {
+	t.Helper()
+	r, err := NewKeyring(overlap, keys...)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	r.now = func() time.Time { return *now }
+	return r
+}
+
+// testKeys returns an HMAC key active from t0, an Ed25519 key from t0+1h and
+// an RSA key from t0+2h, deliberately out of order.
+func testKeys(t *testing.T, t0 time.Time) []SigningKey {
+	t.Helper()
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	ed, err := NewAsymmetricKey("ed", edPrivate, t0.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("NewAsymmetricKey(ed): %v", err)
+	}
+	rs, err := NewAsymmetricKey("rsa", rsaPrivate, t0.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("NewAsymmetricKey(rsa): %v", err)
+	}
+	return []SigningKey{rs, NewHMACKey("hs", []byte("0123456789abcdef0123456789abcdef"), t0), ed}
+}
+
+// signedKid returns the kid header of a token signed by r.
+func signedKid(t *testing.T, r *Keyring) string {
+	t.Helper()
+	token, err := r.Sign(jwt.RegisteredClaims{Subject: "1"})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified: %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestKeyringSignsWithCurrentKey(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := t0
+	r := keyringAt(t, &now, 15*time.Minute, testKeys(t, t0)...)
+
+	tests := []struct {
+		at   time.Duration
+		want string
+	}{
+		{0, "hs"},
+		{59 * time.Minute, "hs"},
+		{time.Hour, "ed"},
+		{2*time.Hour - time.Second, "ed"},
+		{2 * time.Hour, "rsa"},
+		{48 * time.Hour, "rsa"},
+	}
+	for _, tt := range tests {
+		now = t0.Add(tt.at)
+		if got := signedKid(t, r); got != tt.want {
+			t.Errorf("at t0+%s: signed with %q, want %q", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestKeyringRetiresReplacedKeys(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := t0
+	overlap := 15 * time.Minute
+	r := keyringAt(t, &now, overlap, testKeys(t, t0)...)
+	token, err := r.Sign(jwt.RegisteredClaims{Subject: "1"})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		at    time.Duration
+		valid bool
+	}{
+		{"while current", 30 * time.Minute, true},
+		{"replaced, within overlap", time.Hour + overlap, true},
+		{"replaced, after overlap", time.Hour + overlap + time.Second, false},
+	}
+	for _, tt := range tests {
+		now = t0.Add(tt.at)
+		_, err := jwt.Parse(token, r.Keyfunc, jwt.WithTimeFunc(func() time.Time { return now }))
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("%s: valid = %v (err %v), want %v", tt.name, valid, err, tt.valid)
+		}
+	}
+}
+
+func TestKeyringKeyfuncRejectsMismatchedTokens(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := t0.Add(3 * time.Hour)
+	keys := testKeys(t, t0)
+	r := keyringAt(t, &now, 15*time.Minute, keys...)
+
+	// An attacker who knows the public RSA key uses it as an HMAC secret.
+	public, err := x509.MarshalPKIXPublicKey(keys[0].verify)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "1"})
+	forged.Header["kid"] = "rsa"
+	forgedRSA, err := forged.SignedString(public)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	unknown := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "1"})
+	unknown.Header["kid"] = "nope"
+	unknownKid, err := unknown.SignedString([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"HS256 token with an RSA kid", forgedRSA},
+		{"unknown kid", unknownKid},
+	}
+	for _, tt := range tests {
+		if _, err := jwt.Parse(tt.token, r.Keyfunc); err == nil {
+			t.Errorf("%s: token accepted", tt.name)
+		}
+	}
+}
+
+func TestKeyringJWKS(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := t0
+	overlap := 15 * time.Minute
+	r := keyringAt(t, &now, overlap, testKeys(t, t0)...)
+
+	tests := []struct {
+		name string
+		at   time.Duration
+		want []string
+	}{
+		{"HMAC current, others scheduled", 0, []string{"ed", "rsa"}},
+		{"ed current", time.Hour + time.Minute, []string{"ed", "rsa"}},
+		{"ed replaced, within overlap", 2*time.Hour + overlap, []string{"ed", "rsa"}},
+		{"ed retired", 2*time.Hour + overlap + time.Second, []string{"rsa"}},
+	}
+	for _, tt := range tests {
+		now = t0.Add(tt.at)
+		var got []string
+		for _, k := range r.JWKS().Keys {
+			got = append(got, k.KeyID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: JWKS has keys %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"test-backend/internal/logging"
+	"test-backend/internal/problem"
+	"test-backend/internal/validation"
+)
+
+// LogoutRequest is the optional body accepted by Logout.
+type LogoutRequest struct {
+	// RefreshToken, when given, is revoked along with every token rotated from it.
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// Logout godoc
+// @Summary      Log out
+// @Description  revoke the presented access token and, if given, the refresh token of the same session
+// @Tags         auth
+// @Accept       json
+// @Security     BearerAuth
+// @Param        request  body      LogoutRequest  false  "Refresh token"
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      422  {object}  problem.Problem
+// @Router       /logout [post]
+func (h *Handler) Logout(c *gin.Context) {
+	principal, ok := PrincipalFrom(c)
+	if !ok {
+		abortUnauthorized(c, "missing token")
+		return
+	}
+	var req LogoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Error(c, validation.BindError(err))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if err := h.revocations.Revoke(ctx, principal.TokenID, principal.TokenExpiresAt); err != nil {
+		problem.Error(c, err)
+		return
+	}
+	if req.RefreshToken != "" {
+		stored, err := h.refreshTokens.Get(ctx, hashOpaqueToken(req.RefreshToken))
+		// Unknown tokens and tokens of other users are ignored: the session is
+		// over either way and the response must not reveal which tokens exist.
+		if err != nil && !errors.Is(err, ErrInvalidRefreshToken) {
+			problem.Error(c, err)
+			return
+		}
+		if err == nil && stored.UserID == principal.UserID {
+			if err := h.refreshTokens.RevokeFamily(ctx, stored.FamilyID); err != nil {
+				problem.Error(c, err)
+				return
+			}
+		}
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary      Log out everywhere
+// @Description  revoke every access and refresh token issued to the authenticated user
+// @Tags         auth
+// @Security     BearerAuth
+// @Success      204
+// @Failure      401  {object}  problem.Problem
+// @Router       /logout-all [post]
+func (h *Handler) LogoutAll(c *gin.Context) {
+	principal, ok := PrincipalFrom(c)
+	if !ok {
+		abortUnauthorized(c, "missing token")
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.revokeSessions(ctx, principal.UserID); err != nil {
+		problem.Error(c, err)
+		return
+	}
+	logging.FromContext(ctx).Info("all sessions revoked")
+	c.Status(http.StatusNoContent)
+}
+
+// revokeSessions revokes every access and refresh token issued to userID so far.
+func (h *Handler) revokeSessions(ctx context.Context, userID int) error {
+	if err := h.revocations.RevokeUser(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+	return h.refreshTokens.RevokeUser(ctx, userID)
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"test-backend/internal/logging"
+	"test-backend/internal/problem"
+	"test-backend/internal/user"
+	"test-backend/internal/validation"
+)
+
+// ProfileUpdate is the body accepted by UpdateMe. An empty password keeps the current one.
+type ProfileUpdate struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password,omitempty"`
+	// CurrentPassword is required to change the email or password, so a
+	// stolen access token cannot take over the account.
+	CurrentPassword string `json:"current_password,omitempty"`
+}
+
+// LogValue implements slog.LogValuer so the passwords are never logged.
+func (p ProfileUpdate) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("name", p.Name), slog.String("email", p.Email))
+}
+
+// GetMe godoc
+// @Summary      Get current user
+// @Description  get the profile of the authenticated user
+// @Tags         me
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  user.User
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Router       /me [get]
+func (h *Handler) GetMe(c *gin.Context) {
+	principal, ok := PrincipalFrom(c)
+	if !ok {
+		abortUnauthorized(c, "missing token")
+		return
+	}
+	u, err := h.service.GetByID(c.Request.Context(), principal.UserID)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	u.Password = ""
+	c.JSON(http.StatusOK, u)
+}
+
+// UpdateMe godoc
+// @Summary      Update current user
+// @Description  update the profile of the authenticated user; changing the email or password needs current_password, and a changed email has to be verified again
+// @Tags         me
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        profile  body      ProfileUpdate  true  "Profile"
+// @Success      200  {object}  user.User
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      422  {object}  problem.Problem
+// @Router       /me [put]
+func (h *Handler) UpdateMe(c *gin.Context) {
+	principal, ok := PrincipalFrom(c)
+	if !ok {
+		abortUnauthorized(c, "missing token")
+		return
+	}
+	var req ProfileUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Error(c, validation.BindError(err))
+		return
+	}
+	ctx := c.Request.Context()
+	current, err := h.service.GetByID(ctx, principal.UserID)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	updated, err := h.service.UpdateProfile(ctx, principal.UserID,
+		user.User{Name: req.Name, Email: req.Email, Password: req.Password}, req.CurrentPassword)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	if updated.Email != current.Email {
+		if err := h.sendVerification(ctx, updated); err != nil {
+			logging.FromContext(ctx).Error("could not send verification email", "verify_user_id", updated.ID, "error", err)
+		}
+	}
+	updated.Password = ""
+	c.JSON(http.StatusOK, updated)
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"test-backend/internal/apperr"
+	"test-backend/internal/logging"
+	"test-backend/internal/problem"
+	"test-backend/internal/user"
+)
+
+// ErrTokenRevoked is returned for tokens invalidated by logout or a password change.
+var ErrTokenRevoked = apperr.New(apperr.ErrUnauthorized, "token has been revoked")
+
+// JWTMiddleware rejects requests without a valid, unrevoked bearer token for an
+// existing user and stores the caller's Principal on the context for PrincipalFrom.
+func (h *Handler) JWTMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			h.reject(c, TokenMissing, "missing token")
+			return
+		}
+		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+		claims := &Claims{}
+		token, err := jwt.ParseWithClaims(tokenStr, claims, h.tokens.Keys.Keyfunc)
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			h.reject(c, TokenExpired, "token has expired")
+			return
+		}
+		if err != nil || !token.Valid {
+			h.reject(c, TokenInvalid, "invalid token")
+			return
+		}
+		principal, ok := principalFromClaims(claims)
+		if !ok {
+			h.reject(c, TokenInvalid, "invalid token")
+			return
+		}
+
+		ctx := c.Request.Context()
+		revoked, err := h.revocations.IsRevoked(ctx, principal.TokenID)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		if revoked {
+			h.reject(c, TokenRevoked, ErrTokenRevoked.Error())
+			return
+		}
+		u, err := h.service.GetByID(ctx, principal.UserID)
+		if errors.Is(err, user.ErrNotFound) {
+			h.reject(c, TokenUserNotFound, "user no longer exists")
+			return
+		}
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		if err := h.checkSession(ctx, u, claims.IssuedAt.Time); errors.Is(err, ErrTokenRevoked) {
+			h.reject(c, TokenRevoked, err.Error())
+			return
+		} else if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		if err := h.checkVerified(u); err != nil {
+			h.observer.TokenRejected(TokenUnverified)
+			abortWithError(c, err)
+			return
+		}
+
+		// Authorize with the user's current role rather than the one in the
+		// token, so a demotion takes effect immediately.
+		principal.Role = u.Role
+		c.Set(principalKey, principal)
+		logging.AddAttrs(c, "user_id", principal.UserID)
+		c.Next()
+	}
+}
+
+// checkSession returns ErrTokenRevoked if a token issued to u at issuedAt was
+// invalidated by logging out everywhere or changing the password.
+func (h *Handler) checkSession(ctx context.Context, u user.User, issuedAt time.Time) error {
+	cutoff, err := h.revocations.RevokedBefore(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	if u.PasswordChangedAt.After(cutoff) {
+		cutoff = u.PasswordChangedAt
+	}
+	// An access token's iat is rounded down to the second, so compare at that
+	// precision: otherwise a token from logging in again right after the cutoff
+	// would count as issued before it. Tokens issued earlier within the same
+	// second as the cutoff stay valid.
+	if issuedAt.Before(cutoff.Truncate(time.Second)) {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// reject reports a rejected token to the observer and responds with a 401 problem.
+func (h *Handler) reject(c *gin.Context, reason, detail string) {
+	h.observer.TokenRejected(reason)
+	abortUnauthorized(c, detail)
+}
+
+// abortUnauthorized rejects the request with a 401 problem and a Bearer challenge.
+func abortUnauthorized(c *gin.Context, detail string) {
+	c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	problem.Abort(c, http.StatusUnauthorized, detail)
+}
+
+// abortWithError stops the handler chain and responds with the problem for err.
+func abortWithError(c *gin.Context, err error) {
+	if apperr.Status(err) == http.StatusUnauthorized {
+		abortUnauthorized(c, err.Error())
+		return
+	}
+	c.Abort()
+	problem.Error(c, err)
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+
+	"test-backend/internal/user"
+)
+
+// newTestHandler returns a Handler backed by in-memory stores and the user service it uses.
+func newTestHandler(t *testing.T) (*Handler, user.Service) {
+	t.Helper()
+	keys, err := NewKeyring(time.Hour, NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"), time.Time{}))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	service := user.NewService(user.NewInMemoryRepository(), bcrypt.MinCost, nil, user.LockoutPolicy{}, user.NewInMemoryTwoFactorStore())
+	h := NewHandler(service, TokenConfig{Keys: keys, AccessTokenTTL: 15 * time.Minute, RefreshTokenTTL: time.Hour},
+		MailConfig{}, NewInMemoryRefreshStore(), NewInMemoryRevocationStore(), NewInMemoryPasswordResetStore(), nil)
+	return h, service
+}
+
+func TestJWTMiddlewareUsesCurrentRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	h, service := newTestHandler(t)
+	admin, err := service.Create(ctx, user.User{Name: "Admin", Email: "admin@example.com", Password: "Passw0rd!23", Role: user.RoleAdmin})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	token, err := h.generateToken(admin)
+	if err != nil {
+		t.Fatalf("generateToken: %v", err)
+	}
+
+	r := gin.New()
+	r.GET("/users", h.JWTMiddleware(), RequireRole(user.RoleAdmin), func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("before demotion: got status %d, want %d", code, http.StatusOK)
+	}
+	if _, err := service.Update(ctx, admin.ID, user.User{Name: admin.Name, Email: admin.Email, Role: user.RoleViewer}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if code := get(); code != http.StatusForbidden {
+		t.Fatalf("after demotion: got status %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+func TestCheckSessionComparesWholeSeconds(t *testing.T) {
+	ctx := context.Background()
+	h, service := newTestHandler(t)
+	u, err := service.Create(ctx, user.User{Name: "Alice", Email: "alice@example.com", Password: "Passw0rd!23"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	cutoff := time.Date(2026, 1, 1, 12, 0, 0, 500_000_000, time.UTC)
+	if err := h.revocations.RevokeUser(ctx, u.ID, cutoff); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+
+	// Tokens carry iat in whole seconds.
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{"issued the second before", cutoff.Truncate(time.Second).Add(-time.Second), true},
+		{"issued in the same second", cutoff.Truncate(time.Second), false},
+		{"issued after", cutoff.Truncate(time.Second).Add(time.Second), false},
+	}
+	for _, tt := range tests {
+		err := h.checkSession(ctx, u, tt.issuedAt)
+		if revoked := errors.Is(err, ErrTokenRevoked); revoked != tt.revoked || (err != nil && !revoked) {
+			t.Errorf("%s: checkSession = %v, want revoked %v", tt.name, err, tt.revoked)
+		}
+	}
+}
+
+func TestLoginAgainRightAfterRevokingSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h, service := newTestHandler(t)
+	if _, err := service.Create(context.Background(), user.User{Name: "Alice", Email: "alice@example.com", Password: "Passw0rd!23"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	r := gin.New()
+	r.POST("/login", h.Login)
+	authorized := r.Group("/", h.JWTMiddleware())
+	authorized.GET("/me", h.GetMe)
+	authorized.PUT("/me", h.UpdateMe)
+	authorized.POST("/logout-all", h.LogoutAll)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	login := func(password string) string {
+		t.Helper()
+		rec := do(http.MethodPost, "/login", "", `{"email": "alice@example.com", "password": "`+password+`"}`)
+		var tokens TokenResponse
+		if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &tokens) != nil {
+			t.Fatalf("login: status %d: %s", rec.Code, rec.Body)
+		}
+		return tokens.AccessToken
+	}
+
+	token := login("Passw0rd!23")
+	rec := do(http.MethodPut, "/me", token,
+		`{"name": "Alice", "email": "alice@example.com", "password": "N3w-passw0rd", "current_password": "Passw0rd!23"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("change password: status %d: %s", rec.Code, rec.Body)
+	}
+	token = login("N3w-passw0rd")
+	if rec := do(http.MethodGet, "/me", token, ""); rec.Code != http.StatusOK {
+		t.Fatalf("after changing the password: status %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if rec := do(http.MethodPost, "/logout-all", token, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("logout-all: status %d", rec.Code)
+	}
+	token = login("N3w-passw0rd")
+	if rec := do(http.MethodGet, "/me", token, ""); rec.Code != http.StatusOK {
+		t.Fatalf("after logging out everywhere: status %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,36 @@
+package auth
+
+// Observer is notified of authentication outcomes, for example to export metrics.
+type Observer interface {
+	LoginSucceeded()
+	LoginFailed(reason string)
+	TokenRejected(reason string)
+}
+
+// Reasons passed to Observer.LoginFailed.
+const (
+	LoginInvalidRequest     = "invalid_request"
+	LoginInvalidCredentials = "invalid_credentials"
+	LoginLocked             = "locked"
+	LoginUnverified         = "unverified"
+	LoginInvalidCode        = "invalid_code"
+	LoginInvalidChallenge   = "invalid_challenge"
+	LoginError              = "error"
+)
+
+// Reasons passed to Observer.TokenRejected.
+const (
+	TokenMissing      = "missing"
+	TokenInvalid      = "invalid"
+	TokenExpired      = "expired"
+	TokenRevoked      = "revoked"
+	TokenUserNotFound = "user_not_found"
+	TokenUnverified   = "unverified"
+)
+
+// nopObserver is the Observer used when none is given.
+type nopObserver struct{}
+
+func (nopObserver) LoginSucceeded()      {}
+func (nopObserver) LoginFailed(string)   {}
+func (nopObserver) TokenRejected(string) {}
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"test-backend/internal/logging"
+	"test-backend/internal/mail"
+	"test-backend/internal/problem"
+	"test-backend/internal/user"
+	"test-backend/internal/validation"
+)
+
+// MailConfig controls the email Handler sends.
+type MailConfig struct {
+	Mailer mail.Mailer
+	// PasswordResetURL is the page linked from password reset emails. The
+	// reset token is added to it as the token query parameter.
+	PasswordResetURL string
+	// VerificationURL is linked from email verification emails in the same way.
+	// It normally points at the VerifyEmail endpoint.
+	VerificationURL string
+}
+
+// ForgotPasswordRequest is the body accepted by ForgotPassword.
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest is the body accepted by ResetPassword.
+type ResetPasswordRequest struct {
+	// Token is the password reset token from the email sent by ForgotPassword.
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ForgotPassword godoc
+// @Summary      Request a password reset
+// @Description  email a single-use password reset link to the account with the given email, if there is one
+// @Tags         auth
+// @Accept       json
+// @Param        request  body      ForgotPasswordRequest  true  "Email"
+// @Success      202
+// @Failure      400  {object}  problem.Problem
+// @Failure      422  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Router       /password/forgot [post]
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Error(c, validation.BindError(err))
+		return
+	}
+	var v validation.Validator
+	v.Email("email", req.Email)
+	if err := v.Err(); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	u, err := h.service.GetByEmail(ctx, req.Email)
+	if errors.Is(err, user.ErrNotFound) {
+		// Respond as if an email was sent so the response does not reveal
+		// which emails are registered.
+		c.Status(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	// The email is sent after responding and a failure to send is only logged:
+	// waiting for it, or an error here but not for unknown emails, would reveal
+	// that the email is registered.
+	h.sendInBackground(ctx, func(ctx context.Context) error {
+		return h.sendPasswordReset(ctx, u)
+	}, "could not send password reset email", "reset_user_id", u.ID)
+	c.Status(http.StatusAccepted)
+}
+
+// sendInBackground calls send without waiting for it to return, logging its
+// error with msg and args. send gets a context with the values of ctx that is
+// not canceled when the request ends.
+func (h *Handler) sendInBackground(ctx context.Context, send func(ctx context.Context) error, msg string, args ...any) {
+	ctx = context.WithoutCancel(ctx)
+	h.sending.Add(1)
+	go func() {
+		defer h.sending.Done()
+		if err := send(ctx); err != nil {
+			logging.FromContext(ctx).Error(msg, append(args, "error", err)...)
+		}
+	}()
+}
+
+// Wait blocks until the emails being sent in the background have been sent or
+// ctx is done, in which case it returns the context's error.
+func (h *Handler) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.sending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendPasswordReset stores a new reset token for u, replacing any earlier one,
+// and emails it to them.
+func (h *Handler) sendPasswordReset(ctx context.Context, u user.User) error {
+	value, hash, err := newOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("generate password reset token: %w", err)
+	}
+	err = h.resets.Create(ctx, PasswordReset{
+		Hash:      hash,
+		UserID:    u.ID,
+		ExpiresAt: time.Now().Add(h.tokens.PasswordResetTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("store password reset token: %w", err)
+	}
+	link, err := url.Parse(h.mail.PasswordResetURL)
+	if err != nil {
+		return fmt.Errorf("password reset url: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", value)
+	link.RawQuery = query.Encode()
+
+	err = h.mail.Mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your account. To choose a new password, "+
+			"open the link below within %s:\n\n%s\n\nIf you did not ask for this, you can ignore this email.",
+			describeDuration(h.tokens.PasswordResetTTL), link),
+	})
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("password reset requested", "reset_user_id", u.ID)
+	return nil
+}
+
+// describeDuration writes d in whole hours or minutes for use in emails.
+func describeDuration(d time.Duration) string {
+	n, unit := int(d/time.Minute), "minute"
+	if d >= time.Hour && d%time.Hour == 0 {
+		n, unit = int(d/time.Hour), "hour"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  set a new password using a token from a password reset email; every session of the user is revoked
+// @Tags         auth
+// @Accept       json
+// @Param        request  body      ResetPasswordRequest  true  "Reset token and new password"
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      422  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Router       /password/reset [post]
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Error(c, validation.BindError(err))
+		return
+	}
+	// Check the password before consuming the token so a rejected password
+	// does not cost the user their reset link.
+	var v validation.Validator
+	v.Required("token", req.Token)
+	v.Password("password", req.Password)
+	if err := v.Err(); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	reset, err := h.resets.Consume(ctx, hashOpaqueToken(req.Token))
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	if time.Now().After(reset.ExpiresAt) {
+		problem.Error(c, ErrInvalidResetToken)
+		return
+	}
+	u, err := h.service.GetByID(ctx, reset.UserID)
+	if errors.Is(err, user.ErrNotFound) {
+		problem.Error(c, ErrInvalidResetToken)
+		return
+	}
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	u.Password = req.Password
+	if _, err := h.service.Update(ctx, u.ID, u); err != nil {
+		problem.Error(c, err)
+		return
+	}
+	if err := h.revokeSessions(ctx, u.ID); err != nil {
+		problem.Error(c, err)
+		return
+	}
+	logging.FromContext(ctx).Info("password reset", "reset_user_id", u.ID)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"test-backend/internal/mail"
+	"test-backend/internal/user"
+)
+
+// blockingMailer records messages, each Send returning only once release is closed.
+type blockingMailer struct {
+	release chan struct{}
+	mu      sync.Mutex
+	sent    []mail.Message
+}
+
+func (m *blockingMailer) Send(ctx context.Context, msg mail.Message) error {
+	<-m.release
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *blockingMailer) messages() []mail.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]mail.Message(nil), m.sent...)
+}
+
+func TestForgotPasswordSendsInBackground(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h, service := newTestHandler(t)
+	mailer := &blockingMailer{release: make(chan struct{})}
+	h.mail = MailConfig{Mailer: mailer, PasswordResetURL: "http://localhost/reset"}
+	if _, err := service.Create(context.Background(), user.User{Name: "Alice", Email: "alice@example.com", Password: "Passw0rd!23"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	r := gin.New()
+	r.POST("/password/forgot", h.ForgotPassword)
+
+	for _, email := range []string{"alice@example.com", "nobody@example.com"} {
+		req := httptest.NewRequest(http.MethodPost, "/password/forgot", strings.NewReader(`{"email": "`+email+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		// The request context is canceled once the handler returns, as in net/http.
+		ctx, cancel := context.WithCancel(req.Context())
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req.WithContext(ctx))
+		cancel()
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("%s: status %d, want %d", email, rec.Code, http.StatusAccepted)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait while sending: err = %v, want context.DeadlineExceeded", err)
+	}
+	close(mailer.release)
+	if err := h.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	sent := mailer.messages()
+	if len(sent) != 1 || sent[0].To != "alice@example.com" || !strings.Contains(sent[0].Body, "http://localhost/reset?token=") {
+		t.Errorf("sent %+v, want one reset link to alice@example.com", sent)
+	}
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"test-backend/internal/problem"
+	"test-backend/internal/user"
+)
+
+// principalKey is the gin.Context key under which JWTMiddleware stores the caller.
+const principalKey = "auth.principal"
+
+// Claims are the JWT claims issued by this service. The subject is the user ID.
+type Claims struct {
+	jwt.RegisteredClaims
+	// Role is the user's role when the token was issued.
+	Role user.Role `json:"role"`
+}
+
+// Principal identifies the authenticated caller of a request.
+type Principal struct {
+	UserID int
+	// Role is the user's current role, which may differ from the role claim
+	// of the token.
+	Role user.Role
+	// TokenID and TokenExpiresAt describe the access token the caller presented.
+	TokenID        string
+	TokenExpiresAt time.Time
+}
+
+// principalFromClaims builds the Principal described by validated claims.
+func principalFromClaims(claims *Claims) (Principal, bool) {
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil || id <= 0 || !claims.Role.Valid() || claims.ID == "" ||
+		claims.IssuedAt == nil || claims.ExpiresAt == nil || len(claims.Audience) > 0 {
+		return Principal{}, false
+	}
+	return Principal{UserID: id, Role: claims.Role, TokenID: claims.ID, TokenExpiresAt: claims.ExpiresAt.Time}, true
+}
+
+// PrincipalFrom returns the caller stored on c by JWTMiddleware.
+func PrincipalFrom(c *gin.Context) (Principal, bool) {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	p, ok := v.(Principal)
+	return p, ok
+}
+
+// RequireRole allows the request only if the caller, as set by JWTMiddleware,
+// has one of roles. It must run after JWTMiddleware.
+func RequireRole(roles ...user.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFrom(c)
+		if !ok {
+			abortUnauthorized(c, "missing token")
+			return
+		}
+		if !slices.Contains(roles, principal.Role) {
+			problem.Abort(c, http.StatusForbidden, "your role does not allow this action")
+			return
+		}
+		c.Next()
+	}
+}
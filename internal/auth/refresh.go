@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"test-backend/internal/apperr"
+)
+
+// Errors returned when a refresh token is rejected.
+var (
+	ErrInvalidRefreshToken = apperr.New(apperr.ErrUnauthorized, "invalid refresh token")
+	ErrRefreshTokenReused  = apperr.New(apperr.ErrUnauthorized, "refresh token reuse detected, please log in again")
+)
+
+// RefreshToken is a stored refresh token. Only a hash of the token value is kept.
+// Tokens issued by rotating one another share a FamilyID.
+type RefreshToken struct {
+	Hash      string
+	UserID    int
+	FamilyID  string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	Used      bool
+	Revoked   bool
+}
+
+// RefreshStore persists refresh tokens.
+type RefreshStore interface {
+	// Create stores token. Expired and revoked tokens are pruned, as they can
+	// never be used again; used tokens are kept until they expire so their
+	// reuse is still detected.
+	Create(ctx context.Context, token RefreshToken) error
+	// Get returns the token with hash, or ErrInvalidRefreshToken if none exists.
+	Get(ctx context.Context, hash string) (RefreshToken, error)
+	// Use marks the token with hash as used and returns it as it was before the call,
+	// so callers can tell whether it had already been used. It returns
+	// ErrInvalidRefreshToken if no such token exists.
+	Use(ctx context.Context, hash string) (RefreshToken, error)
+	// RevokeFamily revokes every token in a family.
+	RevokeFamily(ctx context.Context, familyID string) error
+	// RevokeUser revokes every token issued to userID.
+	RevokeUser(ctx context.Context, userID int) error
+}
+
+// newOpaqueToken returns a random value for a refresh or password reset token,
+// and the hash under which it is stored.
+func newOpaqueToken() (value, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	value = base64.RawURLEncoding.EncodeToString(b)
+	return value, hashOpaqueToken(value), nil
+}
+
+// newRandomID returns a random identifier for token families and token IDs.
+func newRandomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashOpaqueToken returns the hash under which a token from newOpaqueToken is stored.
+func hashOpaqueToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// InMemoryRefreshStore is an in-memory implementation of RefreshStore.
+// It is safe for concurrent use.
+type InMemoryRefreshStore struct {
+	mu     sync.Mutex
+	tokens map[string]RefreshToken
+}
+
+// NewInMemoryRefreshStore creates a new in-memory refresh token store.
+func NewInMemoryRefreshStore() *InMemoryRefreshStore {
+	return &InMemoryRefreshStore{tokens: make(map[string]RefreshToken)}
+}
+
+func (s *InMemoryRefreshStore) Create(ctx context.Context, token RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for hash, t := range s.tokens {
+		if t.Revoked || now.After(t.ExpiresAt) {
+			delete(s.tokens, hash)
+		}
+	}
+	s.tokens[token.Hash] = token
+	return nil
+}
+
+func (s *InMemoryRefreshStore) Get(ctx context.Context, hash string) (RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[hash]
+	if !ok {
+		return RefreshToken{}, ErrInvalidRefreshToken
+	}
+	return token, nil
+}
+
+func (s *InMemoryRefreshStore) Use(ctx context.Context, hash string) (RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[hash]
+	if !ok {
+		return RefreshToken{}, ErrInvalidRefreshToken
+	}
+	used := token
+	used.Used = true
+	s.tokens[hash] = used
+	return token, nil
+}
+
+func (s *InMemoryRefreshStore) RevokeFamily(ctx context.Context, familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, token := range s.tokens {
+		if token.FamilyID == familyID {
+			token.Revoked = true
+			s.tokens[hash] = token
+		}
+	}
+	return nil
+}
+
+func (s *InMemoryRefreshStore) RevokeUser(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, token := range s.tokens {
+		if token.UserID == userID {
+			token.Revoked = true
+			s.tokens[hash] = token
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SQLiteRefreshStore is a SQLite implementation of RefreshStore.
+type SQLiteRefreshStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteRefreshStore creates a new SQLite refresh token store.
+// The schema is managed by the migrations package.
+func NewSQLiteRefreshStore(db *sql.DB) *SQLiteRefreshStore {
+	return &SQLiteRefreshStore{db: db}
+}
+
+func (s *SQLiteRefreshStore) Create(ctx context.Context, token RefreshToken) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// Two statements rather than one with OR, so each can use its index.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expires_at < ?`, time.Now().UTC()); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE revoked_at IS NOT NULL`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO refresh_tokens (hash, user_id, family_id, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`,
+		token.Hash, token.UserID, token.FamilyID, token.CreatedAt.UTC(), token.ExpiresAt.UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteRefreshStore) Get(ctx context.Context, hash string) (RefreshToken, error) {
+	return getRefreshToken(ctx, s.db, hash)
+}
+
+func (s *SQLiteRefreshStore) Use(ctx context.Context, hash string) (RefreshToken, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	defer tx.Rollback()
+
+	token, err := getRefreshToken(ctx, tx, hash)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	if !token.Used {
+		if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = ? WHERE hash = ?`,
+			time.Now().UTC(), hash); err != nil {
+			return RefreshToken{}, err
+		}
+	}
+	return token, tx.Commit()
+}
+
+// getRefreshToken loads the token with hash using q, which may be a *sql.DB or *sql.Tx.
+func getRefreshToken(ctx context.Context, q interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}, hash string) (RefreshToken, error) {
+	token := RefreshToken{Hash: hash}
+	var usedAt, revokedAt sql.NullTime
+	err := q.QueryRowContext(ctx,
+		`SELECT user_id, family_id, created_at, expires_at, used_at, revoked_at FROM refresh_tokens WHERE hash = ?`, hash).
+		Scan(&token.UserID, &token.FamilyID, &token.CreatedAt, &token.ExpiresAt, &usedAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RefreshToken{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	token.Used = usedAt.Valid
+	token.Revoked = revokedAt.Valid
+	return token, nil
+}
+
+func (s *SQLiteRefreshStore) RevokeFamily(ctx context.Context, familyID string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL`,
+		time.Now().UTC(), familyID)
+	return err
+}
+
+func (s *SQLiteRefreshStore) RevokeUser(ctx context.Context, userID int) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`,
+		time.Now().UTC(), userID)
+	return err
+}
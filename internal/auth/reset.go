@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"test-backend/internal/apperr"
+)
+
+// ErrInvalidResetToken is returned when a password reset token is unknown, used or expired.
+var ErrInvalidResetToken = apperr.New(apperr.ErrBadRequest, "invalid or expired password reset token")
+
+// PasswordReset is a stored password reset token. Only a hash of the token value is kept.
+type PasswordReset struct {
+	Hash      string
+	UserID    int
+	ExpiresAt time.Time
+}
+
+// PasswordResetStore persists password reset tokens.
+type PasswordResetStore interface {
+	// Create stores reset, replacing any token previously issued to the same user.
+	Create(ctx context.Context, reset PasswordReset) error
+	// Consume deletes the token with hash and returns it, so that it can only
+	// be used once. It returns ErrInvalidResetToken if no such token exists.
+	Consume(ctx context.Context, hash string) (PasswordReset, error)
+}
+
+// InMemoryPasswordResetStore is an in-memory implementation of PasswordResetStore.
+// It is safe for concurrent use.
+type InMemoryPasswordResetStore struct {
+	mu     sync.Mutex
+	resets map[string]PasswordReset
+}
+
+// NewInMemoryPasswordResetStore creates a new in-memory password reset store.
+func NewInMemoryPasswordResetStore() *InMemoryPasswordResetStore {
+	return &InMemoryPasswordResetStore{resets: make(map[string]PasswordReset)}
+}
+
+func (s *InMemoryPasswordResetStore) Create(ctx context.Context, reset PasswordReset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Expired tokens are rejected anyway, so forget them.
+	now := time.Now()
+	for hash, r := range s.resets {
+		if r.UserID == reset.UserID || now.After(r.ExpiresAt) {
+			delete(s.resets, hash)
+		}
+	}
+	s.resets[reset.Hash] = reset
+	return nil
+}
+
+func (s *InMemoryPasswordResetStore) Consume(ctx context.Context, hash string) (PasswordReset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reset, ok := s.resets[hash]
+	if !ok {
+		return PasswordReset{}, ErrInvalidResetToken
+	}
+	delete(s.resets, hash)
+	return reset, nil
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SQLitePasswordResetStore is a SQLite implementation of PasswordResetStore.
+type SQLitePasswordResetStore struct {
+	db *sql.DB
+}
+
+// NewSQLitePasswordResetStore creates a new SQLite password reset store.
+// The schema is managed by the migrations package.
+func NewSQLitePasswordResetStore(db *sql.DB) *SQLitePasswordResetStore {
+	return &SQLitePasswordResetStore{db: db}
+}
+
+func (s *SQLitePasswordResetStore) Create(ctx context.Context, reset PasswordReset) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// Expired tokens are rejected anyway, so forget them.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM password_resets WHERE user_id = ? OR expires_at < ?`,
+		reset.UserID, time.Now().UTC()); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO password_resets (hash, user_id, expires_at) VALUES (?, ?, ?)`,
+		reset.Hash, reset.UserID, reset.ExpiresAt.UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLitePasswordResetStore) Consume(ctx context.Context, hash string) (PasswordReset, error) {
+	reset := PasswordReset{Hash: hash}
+	err := s.db.QueryRowContext(ctx, `DELETE FROM password_resets WHERE hash = ? RETURNING user_id, expires_at`, hash).
+		Scan(&reset.UserID, &reset.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return PasswordReset{}, ErrInvalidResetToken
+	}
+	if err != nil {
+		return PasswordReset{}, err
+	}
+	return reset, nil
+}
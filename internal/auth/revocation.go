@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RevocationStore records access tokens that must be rejected before they expire.
+type RevocationStore interface {
+	// Revoke rejects the access token with jti until expiresAt.
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	// RevokeUser rejects every access token issued to userID before the given time.
+	RevokeUser(ctx context.Context, userID int, before time.Time) error
+	// RevokedBefore returns the time set by RevokeUser, or the zero time if none.
+	RevokedBefore(ctx context.Context, userID int) (time.Time, error)
+	// Fail records a failed attempt to use the token with jti, which expires
+	// at expiresAt, and returns the number of failed attempts so far.
+	Fail(ctx context.Context, jti string, expiresAt time.Time) (int, error)
+}
+
+// InMemoryRevocationStore is an in-memory implementation of RevocationStore.
+// It is safe for concurrent use.
+type InMemoryRevocationStore struct {
+	mu       sync.RWMutex
+	tokens   map[string]time.Time
+	cutoffs  map[int]time.Time
+	failures map[string]tokenFailures
+}
+
+type tokenFailures struct {
+	n         int
+	expiresAt time.Time
+}
+
+// NewInMemoryRevocationStore creates a new in-memory revocation store.
+func NewInMemoryRevocationStore() *InMemoryRevocationStore {
+	return &InMemoryRevocationStore{
+		tokens:   make(map[string]time.Time),
+		cutoffs:  make(map[int]time.Time),
+		failures: make(map[string]tokenFailures),
+	}
+}
+
+func (s *InMemoryRevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Expired tokens are rejected anyway, so forget them.
+	now := time.Now()
+	for id, exp := range s.tokens {
+		if now.After(exp) {
+			delete(s.tokens, id)
+		}
+	}
+	s.tokens[jti] = expiresAt
+	return nil
+}
+
+func (s *InMemoryRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.tokens[jti]
+	return ok, nil
+}
+
+func (s *InMemoryRevocationStore) RevokeUser(ctx context.Context, userID int, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if before.After(s.cutoffs[userID]) {
+		s.cutoffs[userID] = before
+	}
+	return nil
+}
+
+func (s *InMemoryRevocationStore) RevokedBefore(ctx context.Context, userID int) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cutoffs[userID], nil
+}
+
+func (s *InMemoryRevocationStore) Fail(ctx context.Context, jti string, expiresAt time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, f := range s.failures {
+		if now.After(f.expiresAt) {
+			delete(s.failures, id)
+		}
+	}
+	f := s.failures[jti]
+	f.n++
+	f.expiresAt = expiresAt
+	s.failures[jti] = f
+	return f.n, nil
+}
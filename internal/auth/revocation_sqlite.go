@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SQLiteRevocationStore is a SQLite implementation of RevocationStore.
+type SQLiteRevocationStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteRevocationStore creates a new SQLite revocation store.
+// The schema is managed by the migrations package.
+func NewSQLiteRevocationStore(db *sql.DB) *SQLiteRevocationStore {
+	return &SQLiteRevocationStore{db: db}
+}
+
+func (s *SQLiteRevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	// Expired tokens are rejected anyway, so forget them.
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < ?`, time.Now().UTC()); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO revoked_tokens (jti, expires_at) VALUES (?, ?) ON CONFLICT (jti) DO NOTHING`,
+		jti, expiresAt.UTC())
+	return err
+}
+
+func (s *SQLiteRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM revoked_tokens WHERE jti = ?`, jti).Scan(&n)
+	return n > 0, err
+}
+
+func (s *SQLiteRevocationStore) RevokeUser(ctx context.Context, userID int, before time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_token_cutoffs (user_id, revoked_before) VALUES (?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET revoked_before = max(revoked_before, excluded.revoked_before)`,
+		userID, before.UTC())
+	return err
+}
+
+func (s *SQLiteRevocationStore) RevokedBefore(ctx context.Context, userID int) (time.Time, error) {
+	var before time.Time
+	err := s.db.QueryRowContext(ctx, `SELECT revoked_before FROM user_token_cutoffs WHERE user_id = ?`, userID).
+		Scan(&before)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return before, err
+}
+
+func (s *SQLiteRevocationStore) Fail(ctx context.Context, jti string, expiresAt time.Time) (int, error) {
+	// Failures only matter while the token is valid.
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM token_failures WHERE expires_at < ?`, time.Now().UTC()); err != nil {
+		return 0, err
+	}
+	var n int
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO token_failures (jti, failures, expires_at) VALUES (?, 1, ?)
+		ON CONFLICT (jti) DO UPDATE SET failures = failures + 1
+		RETURNING failures`,
+		jti, expiresAt.UTC()).Scan(&n)
+	return n, err
+}
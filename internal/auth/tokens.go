@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"test-backend/internal/user"
+)
+
+// TokenConfig controls how Handler signs tokens and how long they live.
+type TokenConfig struct {
+	Keys            *Keyring
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// PasswordResetTTL is how long a password reset link can be used.
+	PasswordResetTTL time.Duration
+	// EmailVerificationTTL is how long an email verification link can be used.
+	EmailVerificationTTL time.Duration
+	// RequireVerifiedEmail refuses to issue or accept tokens for users whose
+	// email is not verified.
+	RequireVerifiedEmail bool
+	// ChallengeTTL is how long the challenge token returned by Login to users
+	// with two-factor authentication can be exchanged for tokens.
+	ChallengeTTL time.Duration
+	// TOTPIssuer names this service in authenticator apps.
+	TOTPIssuer string
+}
+
+// TokenResponse is returned by endpoints that sign a user in.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	// ExpiresIn is the access token lifetime in seconds.
+	ExpiresIn int `json:"expires_in" example:"900"`
+}
+
+// issueTokens signs a new access token for u and stores a new refresh token in
+// familyID, starting a new family when familyID is empty.
+func (h *Handler) issueTokens(ctx context.Context, u user.User, familyID string) (TokenResponse, error) {
+	access, err := h.generateToken(u)
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("generate access token: %w", err)
+	}
+	value, hash, err := newOpaqueToken()
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("generate refresh token: %w", err)
+	}
+	if familyID == "" {
+		if familyID, err = newRandomID(); err != nil {
+			return TokenResponse{}, fmt.Errorf("generate refresh token: %w", err)
+		}
+	}
+	now := time.Now()
+	err = h.refreshTokens.Create(ctx, RefreshToken{
+		Hash:      hash,
+		UserID:    u.ID,
+		FamilyID:  familyID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(h.tokens.RefreshTokenTTL),
+	})
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("store refresh token: %w", err)
+	}
+	return TokenResponse{
+		AccessToken:  access,
+		RefreshToken: value,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(h.tokens.AccessTokenTTL.Seconds()),
+	}, nil
+}
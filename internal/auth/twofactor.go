@@ -0,0 +1,346 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"rsc.io/qr"
+
+	"test-backend/internal/apperr"
+	"test-backend/internal/problem"
+	"test-backend/internal/totp"
+	"test-backend/internal/user"
+	"test-backend/internal/validation"
+)
+
+// ErrInvalidChallenge is returned when a login challenge token is invalid, expired or already used.
+var ErrInvalidChallenge = apperr.New(apperr.ErrUnauthorized, "invalid or expired login challenge")
+
+// challengeAudience marks the tokens Login returns in place of access tokens
+// when a second factor is needed, so they can never pass as access tokens.
+const challengeAudience = "login-2fa"
+
+// ChallengeResponse is returned by Login when the user has to complete the
+// login with LoginSecondFactor.
+type ChallengeResponse struct {
+	ChallengeToken string `json:"challenge_token"`
+	// ExpiresIn is the challenge token lifetime in seconds.
+	ExpiresIn int `json:"expires_in" example:"300"`
+}
+
+// SecondFactorRequest is the body accepted by LoginSecondFactor.
+type SecondFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	// Code is a code from the authenticator app or an unused recovery code.
+	Code string `json:"code"`
+}
+
+// CodeRequest is the body accepted by ConfirmTOTP and DisableTOTP.
+type CodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorStatus is returned by GetTwoFactor.
+type TwoFactorStatus struct {
+	Enabled bool `json:"enabled"`
+}
+
+// TOTPEnrollment is returned by EnrollTOTP.
+type TOTPEnrollment struct {
+	// Secret is the base32 secret for entering into an authenticator app by hand.
+	Secret string `json:"secret"`
+	// ProvisioningURI is the otpauth:// URI authenticator apps read from a QR code.
+	ProvisioningURI string `json:"provisioning_uri"`
+	// QRCode is a PNG image of ProvisioningURI as a data URI.
+	QRCode string `json:"qr_code"`
+}
+
+// RecoveryCodesResponse is returned by ConfirmTOTP.
+type RecoveryCodesResponse struct {
+	// RecoveryCodes can each be used once in place of a TOTP code. They are
+	// not shown again.
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// LoginSecondFactor godoc
+// @Summary      Complete login with a second factor
+// @Description  exchange the challenge token returned by login and a TOTP or recovery code for tokens; failures count towards the login lockout, and a challenge is revoked after 5 wrong codes
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      SecondFactorRequest  true  "Challenge token and code"
+// @Success      200  {object}  TokenResponse
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      422  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Router       /login/2fa [post]
+func (h *Handler) LoginSecondFactor(c *gin.Context) {
+	var req SecondFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.observer.LoginFailed(LoginInvalidRequest)
+		problem.Error(c, validation.BindError(err))
+		return
+	}
+	var v validation.Validator
+	v.Required("challenge_token", req.ChallengeToken)
+	v.Required("code", req.Code)
+	if err := v.Err(); err != nil {
+		h.observer.LoginFailed(LoginInvalidRequest)
+		problem.Error(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	claims := &jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(req.ChallengeToken, claims, h.tokens.Keys.Keyfunc,
+		jwt.WithAudience(challengeAudience), jwt.WithExpirationRequired())
+	id, idErr := strconv.Atoi(claims.Subject)
+	if err != nil || !token.Valid || idErr != nil || claims.ID == "" {
+		h.failLogin(c, ErrInvalidChallenge)
+		return
+	}
+	revoked, err := h.revocations.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		h.failLogin(c, err)
+		return
+	}
+	if revoked {
+		h.failLogin(c, ErrInvalidChallenge)
+		return
+	}
+	u, err := h.service.AuthenticateSecondFactor(ctx, id, req.Code, c.ClientIP())
+	if errors.Is(err, user.ErrNotFound) || errors.Is(err, user.ErrTwoFactorNotEnabled) {
+		err = ErrInvalidChallenge
+	}
+	if errors.Is(err, user.ErrInvalidCode) {
+		if failErr := h.failChallenge(ctx, claims); failErr != nil {
+			err = failErr
+		}
+	}
+	if err != nil {
+		h.failLogin(c, err)
+		return
+	}
+	// The challenge has served its purpose; it must not start another session.
+	if err := h.revocations.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		h.failLogin(c, err)
+		return
+	}
+	tokens, err := h.issueTokens(ctx, u, "")
+	if err != nil {
+		h.failLogin(c, err)
+		return
+	}
+	h.observer.LoginSucceeded()
+	c.JSON(http.StatusOK, tokens)
+}
+
+// maxChallengeFailures is how many wrong codes a challenge token accepts before
+// it is revoked. The account lockout limits guessing across challenges.
+const maxChallengeFailures = 5
+
+// failChallenge counts a wrong code entered for the challenge with claims and
+// revokes the challenge once it has had maxChallengeFailures.
+func (h *Handler) failChallenge(ctx context.Context, claims *jwt.RegisteredClaims) error {
+	n, err := h.revocations.Fail(ctx, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return err
+	}
+	if n < maxChallengeFailures {
+		return nil
+	}
+	return h.revocations.Revoke(ctx, claims.ID, claims.ExpiresAt.Time)
+}
+
+// issueChallenge signs a challenge token that lets u finish logging in with LoginSecondFactor.
+func (h *Handler) issueChallenge(u user.User) (ChallengeResponse, error) {
+	jti, err := newRandomID()
+	if err != nil {
+		return ChallengeResponse{}, fmt.Errorf("generate challenge token: %w", err)
+	}
+	now := time.Now()
+	token, err := h.tokens.Keys.Sign(jwt.RegisteredClaims{
+		ID:        jti,
+		Subject:   strconv.Itoa(u.ID),
+		Audience:  jwt.ClaimStrings{challengeAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(h.tokens.ChallengeTTL)),
+	})
+	if err != nil {
+		return ChallengeResponse{}, fmt.Errorf("sign challenge token: %w", err)
+	}
+	return ChallengeResponse{ChallengeToken: token, ExpiresIn: int(h.tokens.ChallengeTTL.Seconds())}, nil
+}
+
+// GetTwoFactor godoc
+// @Summary      Get two-factor status
+// @Description  report whether the authenticated user has two-factor authentication enabled
+// @Tags         2fa
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  TwoFactorStatus
+// @Failure      401  {object}  problem.Problem
+// @Router       /2fa [get]
+func (h *Handler) GetTwoFactor(c *gin.Context) {
+	principal, ok := PrincipalFrom(c)
+	if !ok {
+		abortUnauthorized(c, "missing token")
+		return
+	}
+	enabled, err := h.service.TwoFactorEnabled(c.Request.Context(), principal.UserID)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, TwoFactorStatus{Enabled: enabled})
+}
+
+// EnrollTOTP godoc
+// @Summary      Start TOTP enrolment
+// @Description  generate a new TOTP secret for the authenticated user; two-factor authentication is enabled once a code from it is confirmed
+// @Tags         2fa
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  TOTPEnrollment
+// @Failure      401  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Router       /2fa/totp [post]
+func (h *Handler) EnrollTOTP(c *gin.Context) {
+	principal, ok := PrincipalFrom(c)
+	if !ok {
+		abortUnauthorized(c, "missing token")
+		return
+	}
+	ctx := c.Request.Context()
+	u, err := h.service.GetByID(ctx, principal.UserID)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	secret, err := h.service.EnrollTOTP(ctx, u.ID)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	uri := totp.URI(h.tokens.TOTPIssuer, u.Email, secret)
+	code, err := qr.Encode(uri, qr.M)
+	if err != nil {
+		problem.Error(c, fmt.Errorf("encode qr code: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, TOTPEnrollment{
+		Secret:          totp.EncodeSecret(secret),
+		ProvisioningURI: uri,
+		QRCode:          "data:image/png;base64," + base64.StdEncoding.EncodeToString(code.PNG()),
+	})
+}
+
+// ConfirmTOTP godoc
+// @Summary      Confirm TOTP enrolment
+// @Description  enable two-factor authentication with a code from the enrolled secret and return single-use recovery codes
+// @Tags         2fa
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      CodeRequest  true  "TOTP code"
+// @Success      200  {object}  RecoveryCodesResponse
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      422  {object}  problem.Problem
+// @Router       /2fa/totp/confirm [post]
+func (h *Handler) ConfirmTOTP(c *gin.Context) {
+	principal, ok := PrincipalFrom(c)
+	if !ok {
+		abortUnauthorized(c, "missing token")
+		return
+	}
+	req, ok := bindCode(c)
+	if !ok {
+		return
+	}
+	codes, err := h.service.ConfirmTOTP(c.Request.Context(), principal.UserID, req.Code)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP godoc
+// @Summary      Disable two-factor authentication
+// @Description  turn two-factor authentication off after checking a TOTP or recovery code
+// @Tags         2fa
+// @Accept       json
+// @Security     BearerAuth
+// @Param        request  body      CodeRequest  true  "TOTP or recovery code"
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      422  {object}  problem.Problem
+// @Router       /2fa/totp/disable [post]
+func (h *Handler) DisableTOTP(c *gin.Context) {
+	principal, ok := PrincipalFrom(c)
+	if !ok {
+		abortUnauthorized(c, "missing token")
+		return
+	}
+	req, ok := bindCode(c)
+	if !ok {
+		return
+	}
+	if err := h.service.DisableTOTP(c.Request.Context(), principal.UserID, req.Code); err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// bindCode reads a CodeRequest, responding with a problem and reporting false if it is invalid.
+func bindCode(c *gin.Context) (CodeRequest, bool) {
+	var req CodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Error(c, validation.BindError(err))
+		return CodeRequest{}, false
+	}
+	var v validation.Validator
+	v.Required("code", req.Code)
+	if err := v.Err(); err != nil {
+		problem.Error(c, err)
+		return CodeRequest{}, false
+	}
+	return req, true
+}
+
+// RequireTwoFactor allows the request only if the caller has two-factor
+// authentication enabled, so signing in to their account takes a second
+// factor. It must run after JWTMiddleware.
+func (h *Handler) RequireTwoFactor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFrom(c)
+		if !ok {
+			abortUnauthorized(c, "missing token")
+			return
+		}
+		enabled, err := h.service.TwoFactorEnabled(c.Request.Context(), principal.UserID)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		if !enabled {
+			problem.Abort(c, http.StatusForbidden, "enable two-factor authentication to perform this action")
+			return
+		}
+		c.Next()
+	}
+}
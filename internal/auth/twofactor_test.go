@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"test-backend/internal/database/dbtest"
+	"test-backend/internal/problem"
+	"test-backend/internal/totp"
+	"test-backend/internal/user"
+)
+
+func TestLoginSecondFactorRevokesChallengeAfterFailures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	stores := map[string]RevocationStore{
+		"memory": NewInMemoryRevocationStore(),
+		"sqlite": NewSQLiteRevocationStore(dbtest.Open(t)),
+	}
+	for backend, revocations := range stores {
+		h, service := newTestHandler(t)
+		h.revocations = revocations
+		h.tokens.ChallengeTTL = time.Minute
+		u, err := service.Create(ctx, user.User{Name: "Alice", Email: "alice@example.com", Password: "Passw0rd!23"})
+		if err != nil {
+			t.Fatalf("%s: Create: %v", backend, err)
+		}
+		secret, err := service.EnrollTOTP(ctx, u.ID)
+		if err != nil {
+			t.Fatalf("%s: EnrollTOTP: %v", backend, err)
+		}
+		step := totp.Step(time.Now())
+		recovery, err := service.ConfirmTOTP(ctx, u.ID, totp.Code(secret, step))
+		if err != nil {
+			t.Fatalf("%s: ConfirmTOTP: %v", backend, err)
+		}
+		// A code that is wrong for every step the service may accept.
+		valid := []string{totp.Code(secret, step-1), totp.Code(secret, step), totp.Code(secret, step+1), totp.Code(secret, step+2)}
+		wrong := "000000"
+		for i := 0; slices.Contains(valid, wrong); i++ {
+			wrong = fmt.Sprintf("%06d", i)
+		}
+
+		r := gin.New()
+		r.POST("/login/2fa", h.LoginSecondFactor)
+		attempt := func(challenge, code string) (int, problem.Problem) {
+			body, _ := json.Marshal(SecondFactorRequest{ChallengeToken: challenge, Code: code})
+			req := httptest.NewRequest(http.MethodPost, "/login/2fa", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			var p problem.Problem
+			json.Unmarshal(rec.Body.Bytes(), &p)
+			return rec.Code, p
+		}
+		challenge := func() string {
+			c, err := h.issueChallenge(u)
+			if err != nil {
+				t.Fatalf("%s: issueChallenge: %v", backend, err)
+			}
+			return c.ChallengeToken
+		}
+
+		// Short of the limit, the right code still completes the login.
+		first := challenge()
+		for i := 1; i < maxChallengeFailures; i++ {
+			if code, p := attempt(first, wrong); code != http.StatusUnauthorized || p.Detail != user.ErrInvalidCode.Error() {
+				t.Fatalf("%s: wrong code %d: status %d, %q", backend, i, code, p.Detail)
+			}
+		}
+		if code, p := attempt(first, recovery[0]); code != http.StatusOK {
+			t.Fatalf("%s: right code after %d failures: status %d, %q", backend, maxChallengeFailures-1, code, p.Detail)
+		}
+
+		// At the limit the challenge is revoked, so even the right code fails.
+		second := challenge()
+		for i := 1; i <= maxChallengeFailures; i++ {
+			if code, p := attempt(second, wrong); code != http.StatusUnauthorized || p.Detail != user.ErrInvalidCode.Error() {
+				t.Fatalf("%s: wrong code %d: status %d, %q", backend, i, code, p.Detail)
+			}
+		}
+		if code, p := attempt(second, recovery[1]); code != http.StatusUnauthorized || p.Detail != ErrInvalidChallenge.Error() {
+			t.Errorf("%s: right code after %d failures: status %d, %q, want the challenge rejected",
+				backend, maxChallengeFailures, code, p.Detail)
+		}
+		// Another challenge is unaffected, and the recovery code was not spent.
+		if code, p := attempt(challenge(), recovery[1]); code != http.StatusOK {
+			t.Errorf("%s: new challenge: status %d, %q", backend, code, p.Detail)
+		}
+	}
+}
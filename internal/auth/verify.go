@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"test-backend/internal/apperr"
+	"test-backend/internal/logging"
+	"test-backend/internal/mail"
+	"test-backend/internal/problem"
+	"test-backend/internal/user"
+	"test-backend/internal/validation"
+)
+
+// Errors returned by the email verification flow.
+var (
+	ErrInvalidVerificationToken = apperr.New(apperr.ErrBadRequest, "invalid or expired verification link")
+	ErrEmailNotVerified         = apperr.New(apperr.ErrForbidden, "email address is not verified")
+)
+
+// verificationAudience marks tokens that verify an email address, so they can
+// never pass as access tokens, which have no audience.
+const verificationAudience = "verify-email"
+
+// verificationClaims are the claims of an email verification token. The
+// subject is the user ID.
+type verificationClaims struct {
+	jwt.RegisteredClaims
+	// Email is the address being verified. The token is void once the user's
+	// email changes.
+	Email string `json:"email"`
+}
+
+// VerificationResponse is returned by VerifyEmail.
+type VerificationResponse struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// ResendVerificationRequest is the body accepted by ResendVerification.
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// VerifyEmail godoc
+// @Summary      Verify email
+// @Description  mark the email of an account as verified using the signed link sent to it
+// @Tags         auth
+// @Produce      json
+// @Param        token  query     string  true  "Verification token"
+// @Success      200  {object}  VerificationResponse
+// @Failure      400  {object}  problem.Problem
+// @Router       /verify-email [get]
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	claims := &verificationClaims{}
+	token, err := jwt.ParseWithClaims(c.Query("token"), claims, h.tokens.Keys.Keyfunc,
+		jwt.WithAudience(verificationAudience), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		problem.Error(c, ErrInvalidVerificationToken)
+		return
+	}
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		problem.Error(c, ErrInvalidVerificationToken)
+		return
+	}
+	u, err := h.service.VerifyEmail(c.Request.Context(), id, claims.Email)
+	if errors.Is(err, user.ErrNotFound) {
+		problem.Error(c, ErrInvalidVerificationToken)
+		return
+	}
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, VerificationResponse{Email: u.Email, EmailVerified: u.EmailVerified})
+}
+
+// ResendVerification godoc
+// @Summary      Resend verification email
+// @Description  send a new verification link to the account with the given email, if it exists and is unverified
+// @Tags         auth
+// @Accept       json
+// @Param        request  body      ResendVerificationRequest  true  "Email"
+// @Success      202
+// @Failure      400  {object}  problem.Problem
+// @Failure      422  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Router       /verify-email/resend [post]
+func (h *Handler) ResendVerification(c *gin.Context) {
+	var req ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Error(c, validation.BindError(err))
+		return
+	}
+	var v validation.Validator
+	v.Email("email", req.Email)
+	if err := v.Err(); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	u, err := h.service.GetByEmail(ctx, req.Email)
+	// Unknown and already verified emails get the same response, so it does
+	// not reveal which emails are registered.
+	if errors.Is(err, user.ErrNotFound) || (err == nil && u.EmailVerified) {
+		c.Status(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	// As in ForgotPassword, the email is sent after responding so the response
+	// and its timing stay the same as for unknown emails.
+	h.sendInBackground(ctx, func(ctx context.Context) error {
+		return h.sendVerification(ctx, u)
+	}, "could not send verification email", "verify_user_id", u.ID)
+	c.Status(http.StatusAccepted)
+}
+
+// sendVerification emails u a signed link that verifies their current email.
+func (h *Handler) sendVerification(ctx context.Context, u user.User) error {
+	now := time.Now()
+	token, err := h.tokens.Keys.Sign(verificationClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(u.ID),
+			Audience:  jwt.ClaimStrings{verificationAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(h.tokens.EmailVerificationTTL)),
+		},
+		Email: u.Email,
+	})
+	if err != nil {
+		return fmt.Errorf("sign verification token: %w", err)
+	}
+	link, err := url.Parse(h.mail.VerificationURL)
+	if err != nil {
+		return fmt.Errorf("verification url: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	err = h.mail.Mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("To confirm that this is your email address, open the link below within %s:\n\n%s\n\n"+
+			"If you did not create an account, you can ignore this email.",
+			describeDuration(h.tokens.EmailVerificationTTL), link),
+	})
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("verification email sent", "verify_user_id", u.ID)
+	return nil
+}
+
+// checkVerified returns ErrEmailNotVerified if verified email is required and u has none.
+func (h *Handler) checkVerified(u user.User) error {
+	if h.tokens.RequireVerifiedEmail && !u.EmailVerified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"test-backend/internal/user"
+)
+
+func TestResendVerificationSendsInBackground(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h, service := newTestHandler(t)
+	mailer := &blockingMailer{release: make(chan struct{})}
+	h.mail = MailConfig{Mailer: mailer, VerificationURL: "http://localhost/verify-email"}
+	h.tokens.EmailVerificationTTL = time.Hour
+	ctx := context.Background()
+	if _, err := service.Create(ctx, user.User{Name: "Alice", Email: "alice@example.com", Password: "Passw0rd!23"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := service.Create(ctx, user.User{Name: "Bob", Email: "bob@example.com", Password: "Passw0rd!23", EmailVerified: true}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	r := gin.New()
+	r.POST("/verify-email/resend", h.ResendVerification)
+
+	for _, email := range []string{"alice@example.com", "bob@example.com", "nobody@example.com"} {
+		req := httptest.NewRequest(http.MethodPost, "/verify-email/resend", strings.NewReader(`{"email": "`+email+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		reqCtx, cancel := context.WithCancel(req.Context())
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req.WithContext(reqCtx))
+		cancel()
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("%s: status %d, want %d", email, rec.Code, http.StatusAccepted)
+		}
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := h.Wait(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait while sending: err = %v, want context.DeadlineExceeded", err)
+	}
+	close(mailer.release)
+	if err := h.Wait(ctx); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	sent := mailer.messages()
+	if len(sent) != 1 || sent[0].To != "alice@example.com" || !strings.Contains(sent[0].Body, "http://localhost/verify-email?token=") {
+		t.Errorf("sent %+v, want one verification link to alice@example.com", sent)
+	}
+}
@@ -0,0 +1,678 @@
+// Package config loads server settings from defaults, an optional YAML file and
+// environment variables, in increasing order of precedence.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+
+	"test-backend/internal/validation"
+)
+
+// Environments the server can run in. Anything but Development is held to the
+// production rules enforced by Validate, and Development has to be asked for
+// explicitly: the default is Production.
+const (
+	Development = "development"
+	Production  = "production"
+)
+
+// DefaultJWTSecret is the signing secret used when none is configured.
+// Validate rejects it outside Development.
+const DefaultJWTSecret = "secret"
+
+// MinJWTSecretLength is the shortest secret, in bytes, accepted outside Development.
+const MinJWTSecretLength = 32
+
+// Config holds every setting the server reads at startup.
+type Config struct {
+	Env     string `yaml:"env"`
+	Addr    string `yaml:"addr"`
+	GinMode string `yaml:"gin_mode"`
+	// TrustedProxies lists the proxy addresses or CIDR ranges whose
+	// X-Forwarded-For header is believed when determining client IPs.
+	TrustedProxies    []string          `yaml:"trusted_proxies"`
+	Server            Server            `yaml:"server"`
+	RateLimit         RateLimit         `yaml:"rate_limit"`
+	Lockout           Lockout           `yaml:"lockout"`
+	Log               Log               `yaml:"log"`
+	Tracing           Tracing           `yaml:"tracing"`
+	Storage           Storage           `yaml:"storage"`
+	JWT               JWT               `yaml:"jwt"`
+	Mail              Mail              `yaml:"mail"`
+	PasswordReset     PasswordReset     `yaml:"password_reset"`
+	EmailVerification EmailVerification `yaml:"email_verification"`
+	TwoFactor         TwoFactor         `yaml:"two_factor"`
+	BcryptCost        int               `yaml:"bcrypt_cost"`
+}
+
+// Server configures HTTP timeouts and shutdown.
+type Server struct {
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// DrainDelay is how long the server keeps accepting requests after a
+	// shutdown signal, giving load balancers time to stop routing to it.
+	DrainDelay time.Duration `yaml:"drain_delay"`
+	// ShutdownTimeout bounds how long in-flight requests and shutdown hooks may take.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// RateLimit configures request throttling for each route group.
+type RateLimit struct {
+	Enabled bool `yaml:"enabled"`
+	// Store is memory, for a single instance, or sqlite, shared by every
+	// instance using the same database.
+	Store string `yaml:"store"`
+	// Auth limits register, login and refresh requests per client IP.
+	Auth Limit `yaml:"auth"`
+	// Login limits login attempts per email address.
+	Login Limit `yaml:"login"`
+	// API limits authenticated requests per user.
+	API Limit `yaml:"api"`
+}
+
+// Limit allows Requests per Per on average, with bursts of up to Burst
+// requests. Burst defaults to Requests.
+type Limit struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per"`
+	Burst    int           `yaml:"burst"`
+}
+
+// parseLimit parses a limit written as requests/period, such as 5/15m.
+func parseLimit(s string) (Limit, error) {
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%q is not of the form requests/period", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil {
+		return Limit{}, fmt.Errorf("%q: invalid request count", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(per))
+	if err != nil {
+		return Limit{}, fmt.Errorf("%q: %w", s, err)
+	}
+	return Limit{Requests: n, Per: d}, nil
+}
+
+// Lockout configures how failed logins lock accounts and client IPs.
+type Lockout struct {
+	Enabled bool `yaml:"enabled"`
+	// Threshold is the number of failed logins after which an account is locked.
+	Threshold int `yaml:"threshold"`
+	// IPThreshold is the number of failed logins, across all accounts, after
+	// which a client IP is locked.
+	IPThreshold int `yaml:"ip_threshold"`
+	// BaseDuration is how long the first lock lasts. It doubles with every
+	// further failure, up to MaxDuration.
+	BaseDuration time.Duration `yaml:"base_duration"`
+	MaxDuration  time.Duration `yaml:"max_duration"`
+	// ResetAfter is how long failures are remembered after the last one.
+	ResetAfter time.Duration `yaml:"reset_after"`
+}
+
+// Log configures the server's structured logs.
+type Log struct {
+	// Format is json or text.
+	Format string `yaml:"format"`
+	// Level is debug, info, warn or error.
+	Level string `yaml:"level"`
+}
+
+// Tracing configures OpenTelemetry trace export.
+type Tracing struct {
+	// Exporter is none, stdout, file or otlp.
+	Exporter string `yaml:"exporter"`
+	// File receives spans as JSON lines when Exporter is file.
+	File string `yaml:"file"`
+	// Endpoint is the OTLP/HTTP collector URL, such as http://localhost:4318.
+	// If empty the standard OTEL_EXPORTER_OTLP_* variables apply.
+	Endpoint    string  `yaml:"endpoint"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// Storage selects the persistence backend.
+type Storage struct {
+	Backend string `yaml:"backend"`
+	DBPath  string `yaml:"db_path"`
+}
+
+// JWT configures token signing and lifetimes.
+type JWT struct {
+	// Secret signs access tokens with HS256. SecretFile, if set, names a file to
+	// read it from instead. When Keys are also set, the secret only signs tokens
+	// until the first of them activates.
+	Secret     string `yaml:"secret"`
+	SecretFile string `yaml:"secret_file"`
+	// Keys are RSA or Ed25519 private keys that take over signing one after
+	// another at their ActiveFrom times.
+	Keys []Key `yaml:"keys"`
+	// RotationOverlap is how long a replaced key still verifies tokens. The
+	// keys also sign email verification links and two-factor challenges, so it
+	// defaults to the longest of AccessTokenTTL, EmailVerification.TTL and
+	// TwoFactor.ChallengeTTL.
+	RotationOverlap time.Duration `yaml:"rotation_overlap"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+}
+
+// Mail configures how email is sent to users.
+type Mail struct {
+	// Transport is smtp, or log or file to record messages instead of sending
+	// them. Validate rejects log outside Development, since messages contain
+	// secrets such as reset links.
+	Transport string `yaml:"transport"`
+	// From is the sender address, optionally with a display name.
+	From string `yaml:"from"`
+	// File receives messages when Transport is file.
+	File string `yaml:"file"`
+	SMTP SMTP   `yaml:"smtp"`
+}
+
+// SMTP configures the SMTP server used to send mail. Without a Username the
+// server is used without authentication.
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// PasswordReset configures the password reset flow.
+type PasswordReset struct {
+	// URL is the page linked from reset emails, which receives the reset token
+	// as the token query parameter.
+	URL string        `yaml:"url"`
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// EmailVerification configures the email verification flow.
+type EmailVerification struct {
+	// Required makes login, token refresh and authenticated routes reject users
+	// whose email is not verified.
+	Required bool `yaml:"required"`
+	// URL is the link sent to verify an email, which receives a signed token
+	// as the token query parameter. It normally points at GET /verify-email.
+	URL string        `yaml:"url"`
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// TwoFactor configures two-factor authentication.
+type TwoFactor struct {
+	// Issuer names this service in authenticator apps.
+	Issuer string `yaml:"issuer"`
+	// ChallengeTTL is how long a user has to enter their second factor after
+	// their password was accepted.
+	ChallengeTTL time.Duration `yaml:"challenge_ttl"`
+	// RequiredForAdmins rejects admin requests from accounts that have not
+	// enabled two-factor authentication.
+	RequiredForAdmins bool `yaml:"required_for_admins"`
+}
+
+// Key is an asymmetric signing key stored in a PEM file.
+type Key struct {
+	ID         string    `yaml:"id"`
+	File       string    `yaml:"file"`
+	ActiveFrom time.Time `yaml:"active_from"`
+}
+
+// Default returns the settings used when nothing else is configured.
+func Default() Config {
+	return Config{
+		Env:  Production,
+		Addr: ":8080",
+		Server: Server{
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		RateLimit: RateLimit{
+			Enabled: true,
+			Store:   "memory",
+			Auth:    Limit{Requests: 10, Per: time.Minute},
+			Login:   Limit{Requests: 5, Per: 15 * time.Minute},
+			API:     Limit{Requests: 300, Per: time.Minute, Burst: 60},
+		},
+		Lockout: Lockout{
+			Enabled:      true,
+			Threshold:    5,
+			IPThreshold:  20,
+			BaseDuration: time.Minute,
+			MaxDuration:  time.Hour,
+			ResetAfter:   24 * time.Hour,
+		},
+		Log: Log{
+			Format: "json",
+			Level:  "info",
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			File:        "traces.jsonl",
+			ServiceName: "test-backend",
+			SampleRatio: 1,
+		},
+		Storage: Storage{
+			Backend: "memory",
+			DBPath:  "data.db",
+		},
+		JWT: JWT{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+		},
+		Mail: Mail{
+			Transport: "log",
+			From:      "noreply@localhost",
+			File:      "mail.log",
+			SMTP:      SMTP{Port: 587},
+		},
+		PasswordReset: PasswordReset{
+			URL: "http://localhost:8080/password/reset",
+			TTL: time.Hour,
+		},
+		EmailVerification: EmailVerification{
+			URL: "http://localhost:8080/verify-email",
+			TTL: 72 * time.Hour,
+		},
+		TwoFactor: TwoFactor{
+			Issuer:       "test-backend",
+			ChallengeTTL: 5 * time.Minute,
+		},
+		BcryptCost: bcrypt.DefaultCost,
+	}
+}
+
+// Load returns the defaults overridden by the YAML file at path, if path is not
+// empty, and then by environment variables. In development DefaultJWTSecret is
+// used if no secret or keys are configured. The result still has to be checked
+// with Validate.
+func Load(path string) (Config, error) {
+	return load(path, os.LookupEnv)
+}
+
+// load is Load with environment variables found by lookup.
+func load(path string, lookup func(string) (string, bool)) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := cfg.loadEnv(lookup); err != nil {
+		return Config{}, err
+	}
+	if cfg.JWT.SecretFile != "" {
+		secret, err := os.ReadFile(cfg.JWT.SecretFile)
+		if err != nil {
+			return Config{}, fmt.Errorf("read jwt secret: %w", err)
+		}
+		cfg.JWT.Secret = strings.TrimSpace(string(secret))
+	}
+	if cfg.IsDevelopment() && cfg.JWT.Secret == "" && len(cfg.JWT.Keys) == 0 {
+		cfg.JWT.Secret = DefaultJWTSecret
+	}
+	if cfg.JWT.RotationOverlap == 0 {
+		cfg.JWT.RotationOverlap = max(cfg.JWT.AccessTokenTTL, cfg.EmailVerification.TTL, cfg.TwoFactor.ChallengeTTL)
+	}
+	if cfg.GinMode == "" {
+		cfg.GinMode = "release"
+		if cfg.IsDevelopment() {
+			cfg.GinMode = "debug"
+		}
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open config file: %w", err)
+	}
+	defer f.Close()
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides c with the environment variables found by lookup.
+func (c *Config) loadEnv(lookup func(string) (string, bool)) error {
+	strs := []struct {
+		name string
+		dst  *string
+	}{
+		{"APP_ENV", &c.Env},
+		{"HTTP_ADDR", &c.Addr},
+		{"GIN_MODE", &c.GinMode},
+		{"RATE_LIMIT_STORE", &c.RateLimit.Store},
+		{"LOG_FORMAT", &c.Log.Format},
+		{"LOG_LEVEL", &c.Log.Level},
+		{"TRACING_EXPORTER", &c.Tracing.Exporter},
+		{"TRACING_FILE", &c.Tracing.File},
+		{"TRACING_ENDPOINT", &c.Tracing.Endpoint},
+		{"OTEL_SERVICE_NAME", &c.Tracing.ServiceName},
+		{"STORAGE", &c.Storage.Backend},
+		{"DB_PATH", &c.Storage.DBPath},
+		{"JWT_SECRET", &c.JWT.Secret},
+		{"JWT_SECRET_FILE", &c.JWT.SecretFile},
+		{"MAIL_TRANSPORT", &c.Mail.Transport},
+		{"MAIL_FROM", &c.Mail.From},
+		{"MAIL_FILE", &c.Mail.File},
+		{"SMTP_HOST", &c.Mail.SMTP.Host},
+		{"SMTP_USERNAME", &c.Mail.SMTP.Username},
+		{"SMTP_PASSWORD", &c.Mail.SMTP.Password},
+		{"PASSWORD_RESET_URL", &c.PasswordReset.URL},
+		{"EMAIL_VERIFICATION_URL", &c.EmailVerification.URL},
+		{"TWO_FACTOR_ISSUER", &c.TwoFactor.Issuer},
+	}
+	for _, s := range strs {
+		if v, ok := lookup(s.name); ok {
+			*s.dst = v
+		}
+	}
+	durations := []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", &c.Server.ReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT", &c.Server.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", &c.Server.IdleTimeout},
+		{"SHUTDOWN_DRAIN_DELAY", &c.Server.DrainDelay},
+		{"SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout},
+		{"JWT_ROTATION_OVERLAP", &c.JWT.RotationOverlap},
+		{"ACCESS_TOKEN_TTL", &c.JWT.AccessTokenTTL},
+		{"REFRESH_TOKEN_TTL", &c.JWT.RefreshTokenTTL},
+		{"LOCKOUT_BASE_DURATION", &c.Lockout.BaseDuration},
+		{"LOCKOUT_MAX_DURATION", &c.Lockout.MaxDuration},
+		{"LOCKOUT_RESET_AFTER", &c.Lockout.ResetAfter},
+		{"PASSWORD_RESET_TTL", &c.PasswordReset.TTL},
+		{"EMAIL_VERIFICATION_TTL", &c.EmailVerification.TTL},
+		{"TWO_FACTOR_CHALLENGE_TTL", &c.TwoFactor.ChallengeTTL},
+	}
+	for _, d := range durations {
+		if v, ok := lookup(d.name); ok {
+			parsed, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", d.name, err)
+			}
+			*d.dst = parsed
+		}
+	}
+	if v, ok := lookup("TRUSTED_PROXIES"); ok {
+		c.TrustedProxies = nil
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				c.TrustedProxies = append(c.TrustedProxies, p)
+			}
+		}
+	}
+	bools := []struct {
+		name string
+		dst  *bool
+	}{
+		{"RATE_LIMIT_ENABLED", &c.RateLimit.Enabled},
+		{"LOCKOUT_ENABLED", &c.Lockout.Enabled},
+		{"EMAIL_VERIFICATION_REQUIRED", &c.EmailVerification.Required},
+		{"TWO_FACTOR_REQUIRED_FOR_ADMINS", &c.TwoFactor.RequiredForAdmins},
+	}
+	for _, b := range bools {
+		if v, ok := lookup(b.name); ok {
+			parsed, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", b.name, err)
+			}
+			*b.dst = parsed
+		}
+	}
+	limits := []struct {
+		name string
+		dst  *Limit
+	}{
+		{"RATE_LIMIT_AUTH", &c.RateLimit.Auth},
+		{"RATE_LIMIT_LOGIN", &c.RateLimit.Login},
+		{"RATE_LIMIT_API", &c.RateLimit.API},
+	}
+	for _, l := range limits {
+		if v, ok := lookup(l.name); ok {
+			parsed, err := parseLimit(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", l.name, err)
+			}
+			*l.dst = parsed
+		}
+	}
+	if v, ok := lookup("TRACING_SAMPLE_RATIO"); ok {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("TRACING_SAMPLE_RATIO: %w", err)
+		}
+		c.Tracing.SampleRatio = ratio
+	}
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{"LOCKOUT_THRESHOLD", &c.Lockout.Threshold},
+		{"LOCKOUT_IP_THRESHOLD", &c.Lockout.IPThreshold},
+		{"SMTP_PORT", &c.Mail.SMTP.Port},
+		{"BCRYPT_COST", &c.BcryptCost},
+	}
+	for _, i := range ints {
+		if v, ok := lookup(i.name); ok {
+			parsed, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", i.name, err)
+			}
+			*i.dst = parsed
+		}
+	}
+	return nil
+}
+
+// IsDevelopment reports whether the server runs in the development environment.
+func (c Config) IsDevelopment() bool {
+	return c.Env == Development
+}
+
+// Validate reports every setting that is missing, out of range or unsafe for the environment.
+func (c Config) Validate() error {
+	var v validation.Validator
+	if c.Env != Development && c.Env != Production {
+		v.Add("env", "must be one of development or production")
+	}
+	v.Required("addr", c.Addr)
+	timeouts := []struct {
+		field string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	}
+	for _, t := range timeouts {
+		if t.value <= 0 {
+			v.Add(t.field, "must be positive")
+		}
+	}
+	if c.Server.DrainDelay < 0 {
+		v.Add("server.drain_delay", "must not be negative")
+	}
+	if c.GinMode != "debug" && c.GinMode != "release" && c.GinMode != "test" {
+		v.Add("gin_mode", "must be one of debug, release or test")
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		v.Add("log.format", "must be one of json or text")
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		v.Add("log.level", "must be one of debug, info, warn or error")
+	}
+	if c.RateLimit.Enabled {
+		switch c.RateLimit.Store {
+		case "memory":
+		case "sqlite":
+			if c.Storage.Backend != "sqlite" {
+				v.Add("rate_limit.store", "can only be sqlite when storage.backend is sqlite")
+			}
+		default:
+			v.Add("rate_limit.store", "must be one of memory or sqlite")
+		}
+		rules := []struct {
+			field string
+			limit Limit
+		}{
+			{"rate_limit.auth", c.RateLimit.Auth},
+			{"rate_limit.login", c.RateLimit.Login},
+			{"rate_limit.api", c.RateLimit.API},
+		}
+		for _, r := range rules {
+			if r.limit.Requests <= 0 {
+				v.Add(r.field+".requests", "must be positive")
+			}
+			if r.limit.Per <= 0 {
+				v.Add(r.field+".per", "must be positive")
+			}
+			if r.limit.Burst < 0 {
+				v.Add(r.field+".burst", "must not be negative")
+			}
+		}
+	}
+	if c.Lockout.Enabled {
+		if c.Lockout.Threshold <= 0 {
+			v.Add("lockout.threshold", "must be positive")
+		}
+		if c.Lockout.IPThreshold <= 0 {
+			v.Add("lockout.ip_threshold", "must be positive")
+		}
+		if c.Lockout.BaseDuration <= 0 {
+			v.Add("lockout.base_duration", "must be positive")
+		}
+		if c.Lockout.MaxDuration < c.Lockout.BaseDuration {
+			v.Add("lockout.max_duration", "must not be shorter than lockout.base_duration")
+		}
+		if c.Lockout.ResetAfter <= 0 {
+			v.Add("lockout.reset_after", "must be positive")
+		}
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	case "file":
+		v.Required("tracing.file", c.Tracing.File)
+	default:
+		v.Add("tracing.exporter", "must be one of none, stdout, file or otlp")
+	}
+	v.Required("tracing.service_name", c.Tracing.ServiceName)
+	v.Range("tracing.sample_ratio", c.Tracing.SampleRatio, 0, 1)
+	switch c.Storage.Backend {
+	case "memory":
+	case "sqlite":
+		v.Required("storage.db_path", c.Storage.DBPath)
+	default:
+		v.Add("storage.backend", "must be one of memory or sqlite")
+	}
+	if len(c.JWT.Keys) == 0 {
+		v.Required("jwt.secret", c.JWT.Secret)
+	}
+	if c.JWT.Secret != "" && !c.IsDevelopment() {
+		if c.JWT.Secret == DefaultJWTSecret {
+			v.Add("jwt.secret", "must be changed from the default outside development")
+		} else if len(c.JWT.Secret) < MinJWTSecretLength {
+			v.Add("jwt.secret", fmt.Sprintf("must be at least %d bytes outside development", MinJWTSecretLength))
+		}
+	}
+	ids := make(map[string]bool, len(c.JWT.Keys))
+	for i, k := range c.JWT.Keys {
+		field := fmt.Sprintf("jwt.keys[%d]", i)
+		if v.Required(field+".id", k.ID) {
+			if ids[k.ID] {
+				v.Add(field+".id", "must be unique")
+			}
+			ids[k.ID] = true
+		}
+		v.Required(field+".file", k.File)
+	}
+	if c.JWT.AccessTokenTTL <= 0 {
+		v.Add("jwt.access_token_ttl", "must be positive")
+	}
+	// Every token signed by a replaced key must stay verifiable until it expires.
+	signed := []struct {
+		field string
+		ttl   time.Duration
+	}{
+		{"jwt.access_token_ttl", c.JWT.AccessTokenTTL},
+		{"email_verification.ttl", c.EmailVerification.TTL},
+		{"two_factor.challenge_ttl", c.TwoFactor.ChallengeTTL},
+	}
+	for _, s := range signed {
+		if c.JWT.RotationOverlap < s.ttl {
+			v.Add("jwt.rotation_overlap", "must not be shorter than "+s.field)
+		}
+	}
+	if c.JWT.RefreshTokenTTL < c.JWT.AccessTokenTTL {
+		v.Add("jwt.refresh_token_ttl", "must not be shorter than jwt.access_token_ttl")
+	}
+	switch c.Mail.Transport {
+	case "log":
+		if !c.IsDevelopment() {
+			v.Add("mail.transport", "must be smtp or file outside development")
+		}
+	case "file":
+		v.Required("mail.file", c.Mail.File)
+	case "smtp":
+		v.Required("mail.smtp.host", c.Mail.SMTP.Host)
+		v.Range("mail.smtp.port", float64(c.Mail.SMTP.Port), 1, 65535)
+	default:
+		v.Add("mail.transport", "must be one of smtp, file or log")
+	}
+	if v.Required("mail.from", c.Mail.From) {
+		if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+			v.Add("mail.from", "must be an email address")
+		}
+	}
+	links := []struct {
+		field string
+		url   string
+		ttl   time.Duration
+	}{
+		{"password_reset", c.PasswordReset.URL, c.PasswordReset.TTL},
+		{"email_verification", c.EmailVerification.URL, c.EmailVerification.TTL},
+	}
+	for _, l := range links {
+		if u, err := url.Parse(l.url); err != nil || !u.IsAbs() {
+			v.Add(l.field+".url", "must be an absolute URL")
+		}
+		if l.ttl <= 0 {
+			v.Add(l.field+".ttl", "must be positive")
+		}
+	}
+	// The issuer is the prefix of the "issuer:account" label in provisioning URIs.
+	if v.Required("two_factor.issuer", c.TwoFactor.Issuer) && strings.Contains(c.TwoFactor.Issuer, ":") {
+		v.Add("two_factor.issuer", "must not contain a colon")
+	}
+	if c.TwoFactor.ChallengeTTL <= 0 {
+		v.Add("two_factor.challenge_ttl", "must be positive")
+	}
+	v.Range("bcrypt_cost", float64(c.BcryptCost), float64(bcrypt.MinCost), float64(bcrypt.MaxCost))
+	return v.Err()
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"test-backend/internal/validation"
+)
+
+// env returns a lookup function for environment variables given as KEY=value.
+func env(vars ...string) func(string) (string, bool) {
+	m := make(map[string]string, len(vars))
+	for _, kv := range vars {
+		k, v, _ := strings.Cut(kv, "=")
+		m[k] = v
+	}
+	return func(k string) (string, bool) {
+		v, ok := m[k]
+		return v, ok
+	}
+}
+
+// fieldErrors returns the fields err reports as invalid.
+func fieldErrors(err error) []string {
+	var errs validation.Errors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	return fields
+}
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestLoadDefaultsToProduction(t *testing.T) {
+	cfg, err := load("", env())
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Env != Production || cfg.JWT.Secret != "" || cfg.GinMode != "release" {
+		t.Errorf("got env %q, secret %q, gin mode %q, want production without a secret in release mode",
+			cfg.Env, cfg.JWT.Secret, cfg.GinMode)
+	}
+	if fields := fieldErrors(cfg.Validate()); !slices.Contains(fields, "jwt.secret") || !slices.Contains(fields, "mail.transport") {
+		t.Errorf("Validate reported %v, want jwt.secret and mail.transport", fields)
+	}
+
+	cfg, err = load("", env("APP_ENV=development"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.JWT.Secret != DefaultJWTSecret {
+		t.Errorf("development secret = %q, want the default", cfg.JWT.Secret)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate in development: %v", err)
+	}
+}
+
+func TestValidateJWTSecret(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    string
+		secret string
+		valid  bool
+	}{
+		{"default secret in development", Development, DefaultJWTSecret, true},
+		{"short secret in development", Development, "short", true},
+		{"default secret in production", Production, DefaultJWTSecret, false},
+		{"short secret in production", Production, testSecret[:MinJWTSecretLength-1], false},
+		{"missing secret in production", Production, "", false},
+		{"long secret in production", Production, testSecret, true},
+	}
+	for _, tt := range tests {
+		cfg, err := load("", env("APP_ENV="+tt.env, "JWT_SECRET="+tt.secret, "MAIL_TRANSPORT=file"))
+		if err != nil {
+			t.Fatalf("%s: load: %v", tt.name, err)
+		}
+		fields := fieldErrors(cfg.Validate())
+		if valid := !slices.Contains(fields, "jwt.secret"); valid != tt.valid {
+			t.Errorf("%s: jwt.secret valid = %v, want %v (errors on %v)", tt.name, valid, tt.valid, fields)
+		}
+	}
+}
+
+func TestRotationOverlap(t *testing.T) {
+	tests := []struct {
+		name  string
+		vars  []string
+		want  time.Duration
+		valid bool
+	}{
+		{"defaults to the email verification TTL", nil, 72 * time.Hour, true},
+		{"follows the longest TTL", []string{"ACCESS_TOKEN_TTL=100h"}, 100 * time.Hour, true},
+		{"follows the challenge TTL", []string{"EMAIL_VERIFICATION_TTL=1m", "TWO_FACTOR_CHALLENGE_TTL=20m"}, 20 * time.Minute, true},
+		{"explicit and long enough", []string{"JWT_ROTATION_OVERLAP=96h"}, 96 * time.Hour, true},
+		{"explicit and too short", []string{"JWT_ROTATION_OVERLAP=1h"}, time.Hour, false},
+	}
+	for _, tt := range tests {
+		cfg, err := load("", env(append([]string{"APP_ENV=development"}, tt.vars...)...))
+		if err != nil {
+			t.Fatalf("%s: load: %v", tt.name, err)
+		}
+		if cfg.JWT.RotationOverlap != tt.want {
+			t.Errorf("%s: RotationOverlap = %s, want %s", tt.name, cfg.JWT.RotationOverlap, tt.want)
+		}
+		fields := fieldErrors(cfg.Validate())
+		if valid := !slices.Contains(fields, "jwt.rotation_overlap"); valid != tt.valid {
+			t.Errorf("%s: jwt.rotation_overlap valid = %v, want %v", tt.name, valid, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Package dbtest provides migrated SQLite databases for tests.
+package dbtest
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"test-backend/internal/database"
+	"test-backend/internal/migrations"
+)
+
+// Open returns a database in a temporary directory with every migration
+// applied. It is closed when the test finishes.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatalf("migrations.New: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,36 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Liveness godoc
+// @Summary      Liveness probe
+// @Description  report that the process is running; dependencies are not checked
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  Report
+// @Router       /healthz [get]
+func (r *Registry) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, Report{Status: StatusUp, Components: []ComponentReport{}})
+}
+
+// Readiness godoc
+// @Summary      Readiness probe
+// @Description  check every registered dependency and report its status and latency
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  Report
+// @Failure      503  {object}  Report
+// @Router       /readyz [get]
+func (r *Registry) Readiness(c *gin.Context) {
+	report := r.Check(c.Request.Context())
+	status := http.StatusOK
+	if report.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
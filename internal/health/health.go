@@ -0,0 +1,110 @@
+// Package health reports whether the server and its dependencies can serve traffic.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Component statuses.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// ErrShuttingDown is reported by readiness once shutdown has begun.
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// Check reports whether a dependency is usable. It should return promptly once ctx is done.
+type Check func(ctx context.Context) error
+
+// Registry holds the readiness checks of every subsystem. It is safe for concurrent use.
+type Registry struct {
+	timeout      time.Duration
+	mu           sync.RWMutex
+	checks       []namedCheck
+	shuttingDown atomic.Bool
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Report is the result of running every check.
+type Report struct {
+	Status     string            `json:"status" example:"up"`
+	Components []ComponentReport `json:"components"`
+}
+
+// ComponentReport is the result of one check.
+type ComponentReport struct {
+	Name      string  `json:"name" example:"users"`
+	Status    string  `json:"status" example:"up"`
+	LatencyMS float64 `json:"latency_ms" example:"0.42"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// NewRegistry creates a Registry that gives each check at most timeout to finish.
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+// Register adds a check under name.
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, namedCheck{name: name, check: check})
+}
+
+// ShutDown marks the server as shutting down, which makes it permanently unready.
+func (r *Registry) ShutDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Check runs every registered check concurrently and reports them in registration order.
+// The report is down if any check fails or the server is shutting down.
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := r.checks
+	r.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	report := Report{Status: StatusUp, Components: make([]ComponentReport, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c namedCheck) {
+			defer wg.Done()
+			start := time.Now()
+			err := c.check(ctx)
+			comp := ComponentReport{
+				Name:      c.name,
+				Status:    StatusUp,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				comp.Status = StatusDown
+				comp.Error = err.Error()
+			}
+			report.Components[i] = comp
+		}(i, c)
+	}
+	wg.Wait()
+
+	if r.shuttingDown.Load() {
+		report.Components = append(report.Components, ComponentReport{
+			Name: "server", Status: StatusDown, Error: ErrShuttingDown.Error(),
+		})
+	}
+	for _, comp := range report.Components {
+		if comp.Status == StatusDown {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
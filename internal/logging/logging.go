@@ -0,0 +1,69 @@
+// Package logging provides structured logging with per-request context and
+// redaction of secrets.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Redacted replaces the value of every attribute whose key looks sensitive.
+const Redacted = "[REDACTED]"
+
+// New returns a logger writing to w in format "json" or "text" at level
+// "debug", "info", "warn" or "error". Attributes with sensitive keys, such as
+// password, secret, token or authorization, are redacted.
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("log level: %w", err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redact}
+	switch format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// redact is a slog ReplaceAttr function that hides sensitive values.
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if Sensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+// Sensitive reports whether values logged under key must be redacted.
+func Sensitive(key string) bool {
+	key = strings.ToLower(key)
+	switch key {
+	case "authorization", "cookie", "set-cookie":
+		return true
+	}
+	return strings.Contains(key, "password") ||
+		strings.Contains(key, "secret") ||
+		key == "token" || strings.HasSuffix(key, "_token")
+}
+
+type loggerKey struct{}
+
+// WithContext returns a copy of ctx carrying logger.
+func WithContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger if there is none.
+// Within a request it includes the request ID and, once authenticated, the user ID.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
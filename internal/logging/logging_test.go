@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", "debug")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	secrets := map[string]string{
+		"password":         "hunter2-password",
+		"new_password":     "hunter2-new",
+		"current_password": "hunter2-current",
+		"token":            "tok-plain",
+		"access_token":     "tok-access",
+		"refresh_token":    "tok-refresh",
+		"Authorization":    "Bearer tok-header",
+		"jwt_secret":       "jwt-secret-value",
+		"Cookie":           "session=cookie-value",
+	}
+	var attrs []any
+	for k, v := range secrets {
+		attrs = append(attrs, k, v)
+	}
+	logger.With("password", "hunter2-with").Info("flat", attrs...)
+	logger.Info("grouped", slog.Group("request", "authorization", "Bearer tok-grouped", "token", "tok-grouped"))
+	logger.Info("kept", "email", "alice@example.com", "token_type", "Bearer")
+
+	out := buf.String()
+	for _, v := range append(values(secrets), "hunter2-with", "tok-grouped") {
+		if strings.Contains(out, v) {
+			t.Errorf("log output contains %q:\n%s", v, out)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d log lines, want 3:\n%s", len(lines), out)
+	}
+	var flat map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &flat); err != nil {
+		t.Fatalf("decode %s: %v", lines[0], err)
+	}
+	for k := range secrets {
+		if flat[k] != Redacted {
+			t.Errorf("%s = %v, want %s", k, flat[k], Redacted)
+		}
+	}
+	if !strings.Contains(lines[2], "alice@example.com") || !strings.Contains(lines[2], `"token_type":"Bearer"`) {
+		t.Errorf("non-sensitive attributes were dropped: %s", lines[2])
+	}
+}
+
+func TestAccessLogOmitsCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", "info")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	r := gin.New()
+	r.Use(RequestID(logger), AccessLog())
+	r.POST("/login", func(c *gin.Context) {
+		AddAttrs(c, "refresh_token", "tok-added")
+		c.Status(http.StatusUnauthorized)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/login?token=tok-query",
+		strings.NewReader(`{"email": "alice@example.com", "password": "hunter2"}`))
+	req.Header.Set("Authorization", "Bearer tok-header")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := buf.String()
+	if !strings.Contains(out, `"route":"/login"`) {
+		t.Fatalf("no access log line: %s", out)
+	}
+	for _, v := range []string{"hunter2", "tok-header", "tok-added", "tok-query"} {
+		if strings.Contains(out, v) {
+			t.Errorf("access log contains %q: %s", v, out)
+		}
+	}
+}
+
+func values(m map[string]string) []string {
+	var vs []string
+	for _, v := range m {
+		vs = append(vs, v)
+	}
+	return vs
+}
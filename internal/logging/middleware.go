@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the request ID in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+// attrsKey is the gin context key holding the attributes added by AddAttrs.
+const attrsKey = "logging.attrs"
+
+// RequestID returns middleware that propagates the caller's X-Request-ID, or
+// generates one if it is missing or malformed, echoes it in the response and
+// puts a logger carrying it, and the trace ID of a sampled request, on the
+// request context.
+func RequestID(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+		reqLogger := logger.With("request_id", id)
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsSampled() {
+			reqLogger = reqLogger.With("trace_id", sc.TraceID().String())
+		}
+		ctx := WithContext(c.Request.Context(), reqLogger)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// AddAttrs adds attributes to the request's context logger and to its access log line.
+func AddAttrs(c *gin.Context, attrs ...any) {
+	ctx := c.Request.Context()
+	c.Request = c.Request.WithContext(WithContext(ctx, FromContext(ctx).With(attrs...)))
+	prev, _ := c.Get(attrsKey)
+	all, _ := prev.([]any)
+	c.Set(attrsKey, append(all, attrs...))
+}
+
+// AccessLog returns middleware that logs one line per request with its route,
+// status and latency, using the context logger set up by RequestID, which must
+// run first.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		// Take the logger now: later handlers replace it through AddAttrs, whose
+		// attributes are appended below instead.
+		logger := FromContext(c.Request.Context())
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", route,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"client_ip", c.ClientIP(),
+			"bytes", max(c.Writer.Size(), 0),
+		}
+		if extra, ok := c.Get(attrsKey); ok {
+			attrs = append(attrs, extra.([]any)...)
+		}
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		logger.Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// validRequestID reports whether id is safe to reuse: non-empty, bounded and
+// limited to characters that cannot break log lines or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			r == '-' || r == '_' || r == '.' || r == ':') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
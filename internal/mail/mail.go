@@ -0,0 +1,97 @@
+// Package mail sends email to users, through SMTP or, for local testing, to the
+// log or a file.
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"test-backend/internal/logging"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// validate rejects header values that could inject further headers.
+func (m Message) validate() error {
+	if strings.ContainsAny(m.To+m.Subject, "\r\n") {
+		return errors.New("mail: recipient and subject must not contain line breaks")
+	}
+	return nil
+}
+
+// Mailer sends email.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer logs messages instead of sending them. Messages may contain
+// secrets such as reset links, so it is only meant for development.
+type LogMailer struct{}
+
+// NewLogMailer creates a new LogMailer.
+func NewLogMailer() LogMailer {
+	return LogMailer{}
+}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("mail not sent, logging it instead", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// FileMailer appends messages to a file instead of sending them.
+// It is safe for concurrent use.
+type FileMailer struct {
+	mu   sync.Mutex
+	path string
+	from string
+}
+
+// NewFileMailer creates a FileMailer writing messages from the given address to path.
+func NewFileMailer(path, from string) *FileMailer {
+	return &FileMailer{path: path, from: from}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := os.OpenFile(m.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("mail: %w", err)
+	}
+	if err := write(f, m.from, msg); err != nil {
+		f.Close()
+		return fmt.Errorf("mail: %w", err)
+	}
+	if _, err := io.WriteString(f, "\r\n"); err != nil {
+		f.Close()
+		return fmt.Errorf("mail: %w", err)
+	}
+	return f.Close()
+}
+
+// write writes msg to w in Internet Message Format.
+func write(w io.Writer, from string, msg Message) error {
+	body := strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n")
+	_, err := fmt.Fprintf(w, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\n"+
+		"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		from, msg.To, mime.QEncoding.Encode("utf-8", msg.Subject), time.Now().Format(time.RFC1123Z), body)
+	return err
+}
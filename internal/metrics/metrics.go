@@ -0,0 +1,105 @@
+// Package metrics exposes Prometheus metrics for HTTP traffic, authentication
+// and repository calls.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics owns a Prometheus registry and the collectors recorded into it.
+type Metrics struct {
+	registry        *prometheus.Registry
+	httpRequests    *prometheus.CounterVec
+	httpDuration    *prometheus.HistogramVec
+	logins          *prometheus.CounterVec
+	tokenRejections *prometheus.CounterVec
+	repoDuration    *prometheus.HistogramVec
+}
+
+// New creates a Metrics with its own registry, including Go runtime and process metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by method, route template and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_logins_total",
+			Help: "Login attempts by result and, for failures, reason.",
+		}, []string{"result", "reason"}),
+		tokenRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_token_rejections_total",
+			Help: "Access tokens rejected by the authentication middleware by reason.",
+		}, []string{"reason"}),
+		repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "repository_operation_duration_seconds",
+			Help:    "Repository call latency by repository, method and result.",
+			Buckets: []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"repository", "method", "result"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration, m.logins, m.tokenRejections, m.repoDuration,
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() gin.HandlerFunc {
+	h := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	return gin.WrapH(h)
+}
+
+// Middleware records the count and latency of every request. Requests are
+// labelled with their route template, such as /users/:id, so path parameters
+// do not create a series each; requests matching no route share "unmatched".
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// LoginSucceeded counts a successful login.
+func (m *Metrics) LoginSucceeded() {
+	m.logins.WithLabelValues("success", "").Inc()
+}
+
+// LoginFailed counts a failed login for reason.
+func (m *Metrics) LoginFailed(reason string) {
+	m.logins.WithLabelValues("failure", reason).Inc()
+}
+
+// TokenRejected counts an access token rejected for reason.
+func (m *Metrics) TokenRejected(reason string) {
+	m.tokenRejections.WithLabelValues(reason).Inc()
+}
+
+// observeRepository records one repository call that started at start.
+func (m *Metrics) observeRepository(repository, method string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.repoDuration.WithLabelValues(repository, method, result).Observe(time.Since(start).Seconds())
+}
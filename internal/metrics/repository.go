@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"test-backend/internal/product"
+	"test-backend/internal/user"
+)
+
+// UserRepository wraps r so every call is timed.
+func (m *Metrics) UserRepository(r user.Repository) user.Repository {
+	return &userRepository{next: r, m: m}
+}
+
+type userRepository struct {
+	next user.Repository
+	m    *Metrics
+}
+
+func (r *userRepository) observe(method string, start time.Time, err error) {
+	r.m.observeRepository("user", method, start, err)
+}
+
+func (r *userRepository) List(ctx context.Context, q user.ListQuery) (user.Page, error) {
+	start := time.Now()
+	page, err := r.next.List(ctx, q)
+	r.observe("List", start, err)
+	return page, err
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int) (user.User, error) {
+	start := time.Now()
+	u, err := r.next.GetByID(ctx, id)
+	r.observe("GetByID", start, err)
+	return u, err
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (user.User, error) {
+	start := time.Now()
+	u, err := r.next.GetByEmail(ctx, email)
+	r.observe("GetByEmail", start, err)
+	return u, err
+}
+
+func (r *userRepository) Create(ctx context.Context, u user.User) (user.User, error) {
+	start := time.Now()
+	created, err := r.next.Create(ctx, u)
+	r.observe("Create", start, err)
+	return created, err
+}
+
+func (r *userRepository) Update(ctx context.Context, id int, u user.User) (user.User, error) {
+	start := time.Now()
+	updated, err := r.next.Update(ctx, id, u)
+	r.observe("Update", start, err)
+	return updated, err
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, id)
+	r.observe("Delete", start, err)
+	return err
+}
+
+func (r *userRepository) RecordLogin(ctx context.Context, id int, at time.Time, ip string) error {
+	start := time.Now()
+	err := r.next.RecordLogin(ctx, id, at, ip)
+	r.observe("RecordLogin", start, err)
+	return err
+}
+
+func (r *userRepository) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.Ping(ctx)
+	r.observe("Ping", start, err)
+	return err
+}
+
+// ProductRepository wraps r so every call is timed.
+func (m *Metrics) ProductRepository(r product.Repository) product.Repository {
+	return &productRepository{next: r, m: m}
+}
+
+type productRepository struct {
+	next product.Repository
+	m    *Metrics
+}
+
+func (r *productRepository) observe(method string, start time.Time, err error) {
+	r.m.observeRepository("product", method, start, err)
+}
+
+func (r *productRepository) List(ctx context.Context, q product.ListQuery) (product.Page, error) {
+	start := time.Now()
+	page, err := r.next.List(ctx, q)
+	r.observe("List", start, err)
+	return page, err
+}
+
+func (r *productRepository) GetByID(ctx context.Context, id int) (product.Product, error) {
+	start := time.Now()
+	p, err := r.next.GetByID(ctx, id)
+	r.observe("GetByID", start, err)
+	return p, err
+}
+
+func (r *productRepository) Create(ctx context.Context, p product.Product) (product.Product, error) {
+	start := time.Now()
+	created, err := r.next.Create(ctx, p)
+	r.observe("Create", start, err)
+	return created, err
+}
+
+func (r *productRepository) Update(ctx context.Context, id int, p product.Product) (product.Product, error) {
+	start := time.Now()
+	updated, err := r.next.Update(ctx, id, p)
+	r.observe("Update", start, err)
+	return updated, err
+}
+
+func (r *productRepository) Delete(ctx context.Context, id int) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, id)
+	r.observe("Delete", start, err)
+	return err
+}
+
+func (r *productRepository) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.Ping(ctx)
+	r.observe("Ping", start, err)
+	return err
+}
@@ -0,0 +1,154 @@
+package migrations
+
+import (
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"test-backend/internal/database"
+)
+
+func openMigrator(t *testing.T) (*Migrator, *sql.DB) {
+	t.Helper()
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	m, err := New(db)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return m, db
+}
+
+// schema returns the definitions of the tables and indexes in db, except
+// schema_migrations and SQLite's own.
+func schema(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT sql FROM sqlite_master
+		WHERE sql IS NOT NULL AND name != 'schema_migrations' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	defer rows.Close()
+	var defs []string
+	for rows.Next() {
+		var def string
+		if err := rows.Scan(&def); err != nil {
+			t.Fatalf("read schema: %v", err)
+		}
+		defs = append(defs, def)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	return defs
+}
+
+// version returns the highest applied migration version, or 0.
+func version(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var v int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v); err != nil {
+		t.Fatalf("read schema version: %v", err)
+	}
+	return v
+}
+
+// checkStatus fails the test unless exactly the first n migrations are applied.
+func checkStatus(t *testing.T, m *Migrator, n int) {
+	t.Helper()
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(statuses) != len(m.migrations) {
+		t.Fatalf("Status: got %d migrations, want %d", len(statuses), len(m.migrations))
+	}
+	for i, s := range statuses {
+		if s.Version != m.migrations[i].Version || s.Applied != (i < n) || s.AppliedAt.IsZero() == s.Applied {
+			t.Errorf("Status[%d] = version %d applied %v at %v, want version %d applied %v",
+				i, s.Version, s.Applied, s.AppliedAt, m.migrations[i].Version, i < n)
+		}
+	}
+}
+
+func versions(migs []Migration) []int {
+	var vs []int
+	for _, m := range migs {
+		vs = append(vs, m.Version)
+	}
+	return vs
+}
+
+func TestMigratorUpDownUp(t *testing.T) {
+	m, db := openMigrator(t)
+	all := versions(m.migrations)
+	head := all[len(all)-1]
+	checkStatus(t, m, 0)
+
+	ran, err := m.Up()
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if !slices.Equal(versions(ran), all) {
+		t.Fatalf("Up ran %v, want %v", versions(ran), all)
+	}
+	if v := version(t, db); v != head {
+		t.Fatalf("schema version %d, want %d", v, head)
+	}
+	checkStatus(t, m, len(all))
+	migrated := schema(t, db)
+
+	// Roll back over a populated users table, which 0012 and earlier drop columns from.
+	if _, err := db.Exec(`INSERT INTO users (name, email, role) VALUES ('Alice', 'alice@example.com', 'admin')`); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	if ran, err := m.Up(); err != nil || len(ran) != 0 {
+		t.Fatalf("Up at head ran %v, %v, want nothing", versions(ran), err)
+	}
+
+	reverted, err := m.Down(2)
+	if err != nil {
+		t.Fatalf("Down(2): %v", err)
+	}
+	if want := all[len(all)-2:]; !slices.Equal(versions(reverted), []int{want[1], want[0]}) {
+		t.Fatalf("Down(2) reverted %v, want the last two in reverse", versions(reverted))
+	}
+	checkStatus(t, m, len(all)-2)
+	var email string
+	if err := db.QueryRow(`SELECT email FROM users WHERE name = 'Alice'`).Scan(&email); err != nil || email != "alice@example.com" {
+		t.Fatalf("user after Down(2): %q, %v", email, err)
+	}
+
+	reverted, err = m.Down(len(all))
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if len(reverted) != len(all)-2 || reverted[len(reverted)-1].Version != all[0] {
+		t.Fatalf("Down reverted %v, want the remaining %d down to %d", versions(reverted), len(all)-2, all[0])
+	}
+	if v := version(t, db); v != 0 {
+		t.Fatalf("schema version %d after rolling everything back, want 0", v)
+	}
+	if s := schema(t, db); len(s) != 0 {
+		t.Fatalf("schema after rolling everything back: %v", s)
+	}
+	checkStatus(t, m, 0)
+	if reverted, err := m.Down(1); err != nil || len(reverted) != 0 {
+		t.Fatalf("Down with nothing applied reverted %v, %v, want nothing", versions(reverted), err)
+	}
+
+	if _, err := m.Up(); err != nil {
+		t.Fatalf("Up again: %v", err)
+	}
+	if v := version(t, db); v != head {
+		t.Fatalf("schema version %d after migrating up again, want %d", v, head)
+	}
+	if s := schema(t, db); !slices.Equal(s, migrated) {
+		t.Errorf("schema after down and up again differs:\n got %v\nwant %v", s, migrated)
+	}
+	checkStatus(t, m, len(all))
+}
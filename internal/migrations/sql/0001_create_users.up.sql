@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS users (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	name     TEXT NOT NULL DEFAULT '',
+	email    TEXT NOT NULL,
+	password TEXT NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS products (
+	id    INTEGER PRIMARY KEY AUTOINCREMENT,
+	name  TEXT NOT NULL DEFAULT '',
+	price REAL NOT NULL DEFAULT 0
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_token_cutoffs;
+
+DROP TABLE IF EXISTS revoked_tokens;
+
+ALTER TABLE users DROP COLUMN password_changed_at;
@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+	jti        TEXT PRIMARY KEY,
+	expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_token_cutoffs (
+	user_id        INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	revoked_before TIMESTAMP NOT NULL
+);
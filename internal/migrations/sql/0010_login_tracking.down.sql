@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS login_lockouts;
+
+ALTER TABLE users DROP COLUMN last_login_ip;
+ALTER TABLE users DROP COLUMN last_login_at;
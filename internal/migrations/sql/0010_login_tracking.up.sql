@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN last_login_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN last_login_ip TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS login_lockouts (
+	kind            TEXT NOT NULL,
+	key             TEXT NOT NULL,
+	failures        INTEGER NOT NULL,
+	last_failure_at TIMESTAMP NOT NULL,
+	locked_until    TIMESTAMP,
+	PRIMARY KEY (kind, key)
+);
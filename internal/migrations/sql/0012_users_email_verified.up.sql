@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT 0;
+
+-- Accounts created before email verification existed are treated as verified,
+-- so requiring verification does not lock them out.
+UPDATE users SET email_verified = 1;
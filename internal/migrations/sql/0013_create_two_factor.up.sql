@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS two_factor (
+	user_id   INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	secret    BLOB NOT NULL,
+	enabled   BOOLEAN NOT NULL DEFAULT 0,
+	last_step INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+	user_id INTEGER NOT NULL REFERENCES two_factor(user_id) ON DELETE CASCADE,
+	hash    TEXT NOT NULL,
+	PRIMARY KEY (user_id, hash)
+);
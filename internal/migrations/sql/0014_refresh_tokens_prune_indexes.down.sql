@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_revoked_at;
+
+DROP INDEX IF EXISTS idx_refresh_tokens_expires_at;
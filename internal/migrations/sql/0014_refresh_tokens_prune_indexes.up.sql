@@ -0,0 +1,5 @@
+-- Let the pruning in SQLiteRefreshStore.Create find expired and revoked tokens
+-- without scanning the table.
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_revoked_at ON refresh_tokens(revoked_at) WHERE revoked_at IS NOT NULL;
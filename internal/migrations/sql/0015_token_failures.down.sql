@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_token_failures_expires_at;
+
+DROP TABLE IF EXISTS token_failures;
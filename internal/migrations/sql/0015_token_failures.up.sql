@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS token_failures (
+	jti        TEXT PRIMARY KEY,
+	failures   INTEGER NOT NULL,
+	expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_failures_expires_at ON token_failures(expires_at);
@@ -0,0 +1,70 @@
+// Package problem renders RFC 7807 problem details responses.
+package problem
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"test-backend/internal/apperr"
+	"test-backend/internal/logging"
+	"test-backend/internal/validation"
+)
+
+// ContentType is the media type of problem details responses.
+const ContentType = "application/problem+json"
+
+// DefaultType is used when a problem has no more specific type URI.
+const DefaultType = "about:blank"
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type     string `json:"type" example:"about:blank"`
+	Title    string `json:"title" example:"Not Found"`
+	Status   int    `json:"status" example:"404"`
+	Detail   string `json:"detail,omitempty" example:"product not found"`
+	Instance string `json:"instance,omitempty" example:"/products/42"`
+	// Errors lists the fields that failed validation.
+	Errors validation.Errors `json:"errors,omitempty"`
+}
+
+// New creates a problem with the standard title for status.
+func New(status int, detail string) Problem {
+	return Problem{Type: DefaultType, Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+// FromError creates the problem describing err. Details of unexpected errors are hidden.
+func FromError(err error) Problem {
+	p := New(apperr.Status(err), apperr.Message(err))
+	var fields validation.Errors
+	if errors.As(err, &fields) {
+		p.Detail = "one or more fields are invalid"
+		p.Errors = fields
+	}
+	return p
+}
+
+// Write sends p as the response. Instance defaults to the request path.
+func Write(c *gin.Context, p Problem) {
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	c.Header("Content-Type", ContentType)
+	c.JSON(p.Status, p)
+}
+
+// Error responds with the problem describing err and logs unexpected errors.
+func Error(c *gin.Context, err error) {
+	p := FromError(err)
+	if p.Status >= http.StatusInternalServerError {
+		logging.FromContext(c.Request.Context()).Error("request failed", "error", err)
+	}
+	Write(c, p)
+}
+
+// Abort stops the handler chain and responds with a problem for status.
+func Abort(c *gin.Context, status int, detail string) {
+	c.Abort()
+	Write(c, New(status, detail))
+}
@@ -0,0 +1,8 @@
+package product
+
+import "test-backend/internal/apperr"
+
+// Errors returned by Repository and Service.
+var (
+	ErrNotFound = apperr.New(apperr.ErrNotFound, "product not found")
+)
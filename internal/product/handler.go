@@ -3,8 +3,12 @@ package product
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+
+	"test-backend/internal/problem"
+	"test-backend/internal/validation"
 )
 
 // Handler handles HTTP requests for products.
@@ -19,14 +23,70 @@ func NewHandler(s Service) *Handler {
 
 // GetProducts godoc
 // @Summary      List products
-// @Description  get products
+// @Description  list products with filtering, sorting and offset or cursor pagination
 // @Tags         products
 // @Produce      json
 // @Security     BearerAuth
-// @Success      200  {array}   Product
+// @Param        limit      query     int     false  "Page size (1-100)"  default(20)
+// @Param        offset     query     int     false  "Number of matching products to skip"
+// @Param        cursor     query     string  false  "Cursor from a previous page's next_cursor; overrides offset"
+// @Param        sort       query     string  false  "Sort field, prefix with - for descending"  Enums(id, -id, name, -name, price, -price)
+// @Param        min_price  query     number  false  "Minimum price, inclusive"
+// @Param        max_price  query     number  false  "Maximum price, inclusive"
+// @Param        name       query     string  false  "Case-insensitive name substring"
+// @Success      200  {object}  Page
+// @Failure      401  {object}  problem.Problem
+// @Failure      422  {object}  problem.Problem
 // @Router       /products [get]
 func (h *Handler) GetProducts(c *gin.Context) {
-	c.JSON(http.StatusOK, h.service.GetAll())
+	q, err := parseListQuery(c)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	page, err := h.service.List(c.Request.Context(), q)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// parseListQuery reads a ListQuery from the request's query string.
+func parseListQuery(c *gin.Context) (ListQuery, error) {
+	var v validation.Validator
+	q := ListQuery{Cursor: c.Query("cursor"), Name: c.Query("name")}
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			v.Add("limit", "must be an integer")
+		}
+		q.Limit = n
+	}
+	if s := c.Query("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			v.Add("offset", "must be an integer")
+		}
+		q.Offset = n
+	}
+	sort := c.Query("sort")
+	q.Desc = strings.HasPrefix(sort, "-")
+	q.Sort = SortField(strings.TrimPrefix(sort, "-"))
+	for _, bound := range []struct {
+		field string
+		dst   **float64
+	}{{"min_price", &q.MinPrice}, {"max_price", &q.MaxPrice}} {
+		if s := c.Query(bound.field); s != "" {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				v.Add(bound.field, "must be a number")
+				continue
+			}
+			*bound.dst = &f
+		}
+	}
+	return q, v.Err()
 }
 
 // GetProduct godoc
@@ -37,17 +97,19 @@ func (h *Handler) GetProducts(c *gin.Context) {
 // @Security     BearerAuth
 // @Param        id   path      int  true  "Product ID"
 // @Success      200  {object}  Product
-// @Failure      404  {string}  string  "not found"
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
 // @Router       /products/{id} [get]
 func (h *Handler) GetProduct(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.Write(c, problem.New(http.StatusBadRequest, "invalid id"))
 		return
 	}
-	product, ok := h.service.GetByID(id)
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	product, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		problem.Error(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, product)
@@ -62,14 +124,22 @@ func (h *Handler) GetProduct(c *gin.Context) {
 // @Security     BearerAuth
 // @Param        product  body      Product  true  "Product"
 // @Success      201   {object}  Product
+// @Failure      400   {object}  problem.Problem
+// @Failure      401   {object}  problem.Problem
+// @Failure      403   {object}  problem.Problem
+// @Failure      422   {object}  problem.Problem
 // @Router       /products [post]
 func (h *Handler) CreateProduct(c *gin.Context) {
 	var product Product
 	if err := c.ShouldBindJSON(&product); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Error(c, validation.BindError(err))
+		return
+	}
+	created, err := h.service.Create(c.Request.Context(), product)
+	if err != nil {
+		problem.Error(c, err)
 		return
 	}
-	created := h.service.Create(product)
 	c.JSON(http.StatusCreated, created)
 }
 
@@ -83,22 +153,26 @@ func (h *Handler) CreateProduct(c *gin.Context) {
 // @Param        id    path      int       true  "Product ID"
 // @Param        product  body      Product true  "Product"
 // @Success      200   {object}  Product
-// @Failure      404   {string}  string    "not found"
+// @Failure      400   {object}  problem.Problem
+// @Failure      401   {object}  problem.Problem
+// @Failure      403   {object}  problem.Problem
+// @Failure      404   {object}  problem.Problem
+// @Failure      422   {object}  problem.Problem
 // @Router       /products/{id} [put]
 func (h *Handler) UpdateProduct(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.Write(c, problem.New(http.StatusBadRequest, "invalid id"))
 		return
 	}
 	var product Product
 	if err := c.ShouldBindJSON(&product); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Error(c, validation.BindError(err))
 		return
 	}
-	updated, ok := h.service.Update(id, product)
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	updated, err := h.service.Update(c.Request.Context(), id, product)
+	if err != nil {
+		problem.Error(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, updated)
@@ -112,16 +186,19 @@ func (h *Handler) UpdateProduct(c *gin.Context) {
 // @Security     BearerAuth
 // @Param        id   path      int  true  "Product ID"
 // @Success      204  {string}  string  ""
-// @Failure      404  {string}  string  "not found"
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
 // @Router       /products/{id} [delete]
 func (h *Handler) DeleteProduct(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.Write(c, problem.New(http.StatusBadRequest, "invalid id"))
 		return
 	}
-	if !h.service.Delete(id) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		problem.Error(c, err)
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -0,0 +1,78 @@
+package product
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"test-backend/internal/problem"
+	"test-backend/internal/validation"
+)
+
+// newTestRouter serves the product routes from repo.
+func newTestRouter(repo Repository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(NewService(repo))
+	r := gin.New()
+	r.GET("/products", h.GetProducts)
+	r.POST("/products", h.CreateProduct)
+	return r
+}
+
+// serve sends a request to r and decodes a problem response, if any.
+func serve(r http.Handler, method, target, body string) (*httptest.ResponseRecorder, problem.Problem) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	var p problem.Problem
+	if rec.Header().Get("Content-Type") == problem.ContentType {
+		json.Unmarshal(rec.Body.Bytes(), &p)
+	}
+	return rec, p
+}
+
+// decodePage decodes a listing response.
+func decodePage(t *testing.T, body []byte) Page {
+	t.Helper()
+	var page Page
+	if err := json.Unmarshal(body, &page); err != nil {
+		t.Fatalf("decode page: %v", err)
+	}
+	return page
+}
+
+func TestCreateProductValidation(t *testing.T) {
+	r := newTestRouter(NewInMemoryRepository())
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		errors validation.Errors
+	}{
+		{"valid", `{"name": "Lamp", "price": 19.5}`, http.StatusCreated, nil},
+		{"missing fields", `{}`, http.StatusUnprocessableEntity,
+			validation.Errors{{Field: "name", Reason: "is required"}}},
+		{"out of range", `{"name": "Lamp", "price": -1}`, http.StatusUnprocessableEntity,
+			validation.Errors{{Field: "price", Reason: "must be between 0 and 1000000"}}},
+		{"wrong type", `{"name": "Lamp", "price": "cheap"}`, http.StatusUnprocessableEntity,
+			validation.Errors{{Field: "price", Reason: "must be a number"}}},
+		{"malformed", `{"name":`, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		rec, p := serve(r, http.MethodPost, "/products", tt.body)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.status)
+			continue
+		}
+		if !slices.Equal(p.Errors, tt.errors) {
+			t.Errorf("%s: errors %v, want %v", tt.name, p.Errors, tt.errors)
+		}
+	}
+}
@@ -1,8 +1,26 @@
 package product
 
+import "test-backend/internal/validation"
+
+// Limits on product fields.
+const (
+	MaxNameLength = 255
+	MaxPrice      = 1_000_000
+)
+
 // Product represents a product in the system.
 type Product struct {
 	ID    int     `json:"id"`
 	Name  string  `json:"name"`
 	Price float64 `json:"price"`
 }
+
+// Validate checks the product's fields.
+func (p Product) Validate() error {
+	var v validation.Validator
+	if v.Required("name", p.Name) {
+		v.MaxLength("name", p.Name, MaxNameLength)
+	}
+	v.Range("price", p.Price, 0, MaxPrice)
+	return v.Err()
+}
@@ -0,0 +1,55 @@
+package product
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"test-backend/internal/apperr"
+	"test-backend/internal/validation"
+)
+
+func TestProductValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		product Product
+		want    validation.Errors
+	}{
+		{"valid", Product{Name: "Lamp", Price: 19.5}, nil},
+		{"free", Product{Name: "Sticker", Price: 0}, nil},
+		{"most expensive", Product{Name: "Yacht", Price: MaxPrice}, nil},
+		{"missing name", Product{Price: 1},
+			validation.Errors{{Field: "name", Reason: "is required"}}},
+		{"blank name", Product{Name: "   ", Price: 1},
+			validation.Errors{{Field: "name", Reason: "is required"}}},
+		{"name too long", Product{Name: strings.Repeat("ä", MaxNameLength+1), Price: 1},
+			validation.Errors{{Field: "name", Reason: "must be at most 255 characters"}}},
+		{"negative price", Product{Name: "Lamp", Price: -0.01},
+			validation.Errors{{Field: "price", Reason: "must be between 0 and 1000000"}}},
+		{"price too high", Product{Name: "Lamp", Price: MaxPrice + 1},
+			validation.Errors{{Field: "price", Reason: "must be between 0 and 1000000"}}},
+		{"every field reported", Product{Price: -1},
+			validation.Errors{
+				{Field: "name", Reason: "is required"},
+				{Field: "price", Reason: "must be between 0 and 1000000"},
+			}},
+	}
+	for _, tt := range tests {
+		err := tt.product.Validate()
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("%s: Validate = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+		var got validation.Errors
+		if !errors.As(err, &got) || !errors.Is(err, apperr.ErrInvalid) {
+			t.Errorf("%s: Validate = %v, want validation errors", tt.name, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Validate = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
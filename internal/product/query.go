@@ -0,0 +1,159 @@
+package product
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"test-backend/internal/validation"
+)
+
+// Paging limits for List.
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// SortField is a product field that List can order by.
+type SortField string
+
+// Sortable fields.
+const (
+	SortByID    SortField = "id"
+	SortByName  SortField = "name"
+	SortByPrice SortField = "price"
+)
+
+// ListQuery filters, orders and pages a product listing.
+type ListQuery struct {
+	// Limit is the maximum number of items to return.
+	Limit int
+	// Offset skips that many matching items. It is ignored when Cursor is set.
+	Offset int
+	// Cursor continues a previous listing from its Page.NextCursor.
+	Cursor string
+	Sort   SortField
+	Desc   bool
+	// MinPrice and MaxPrice bound the price inclusively when set.
+	MinPrice *float64
+	MaxPrice *float64
+	// Name matches products whose name contains it, ignoring case.
+	Name string
+}
+
+// Page is one page of a product listing.
+type Page struct {
+	Items []Product `json:"items"`
+	// Total is the number of products matching the filters across all pages.
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursor is the decoded form of ListQuery.Cursor: the sort key of the last item
+// on the previous page, plus the ordering it was produced with.
+type cursor struct {
+	Sort  SortField `json:"s"`
+	Desc  bool      `json:"d,omitempty"`
+	ID    int       `json:"i"`
+	Name  string    `json:"n,omitempty"`
+	Price float64   `json:"p,omitempty"`
+}
+
+// Normalize fills in defaults and validates q.
+func (q *ListQuery) Normalize() error {
+	var v validation.Validator
+	switch {
+	case q.Limit == 0:
+		q.Limit = DefaultLimit
+	case q.Limit < 1 || q.Limit > MaxLimit:
+		v.Range("limit", float64(q.Limit), 1, MaxLimit)
+	}
+	if q.Offset < 0 {
+		v.Add("offset", "must not be negative")
+	}
+	if q.Sort == "" {
+		q.Sort = SortByID
+	}
+	if q.Sort != SortByID && q.Sort != SortByName && q.Sort != SortByPrice {
+		v.Add("sort", "must be one of id, name or price")
+	}
+	if q.MinPrice != nil && q.MaxPrice != nil && *q.MinPrice > *q.MaxPrice {
+		v.Add("min_price", "must not be greater than max_price")
+	}
+	if q.Cursor != "" {
+		q.Offset = 0
+		if c, ok := q.cursor(); !ok {
+			v.Add("cursor", "is invalid")
+		} else if c.Sort != q.Sort || c.Desc != q.Desc {
+			v.Add("cursor", "was issued for a different sort order")
+		}
+	}
+	return v.Err()
+}
+
+func (q ListQuery) cursor() (cursor, bool) {
+	var c cursor
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil || json.Unmarshal(raw, &c) != nil || c.ID == 0 {
+		return cursor{}, false
+	}
+	return c, true
+}
+
+// cursorAfter returns the cursor that continues the listing after p.
+func (q ListQuery) cursorAfter(p Product) string {
+	c := cursor{Sort: q.Sort, Desc: q.Desc, ID: p.ID}
+	switch q.Sort {
+	case SortByName:
+		c.Name = p.Name
+	case SortByPrice:
+		c.Price = p.Price
+	}
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// matches reports whether p passes the query's filters.
+func (q ListQuery) matches(p Product) bool {
+	if q.MinPrice != nil && p.Price < *q.MinPrice {
+		return false
+	}
+	if q.MaxPrice != nil && p.Price > *q.MaxPrice {
+		return false
+	}
+	if q.Name != "" && !strings.Contains(strings.ToLower(p.Name), strings.ToLower(q.Name)) {
+		return false
+	}
+	return true
+}
+
+// compare orders a before b by the query's sort field, breaking ties by ID.
+// It returns a negative number, zero or a positive number.
+func (q ListQuery) compare(a, b Product) int {
+	c := 0
+	switch q.Sort {
+	case SortByName:
+		c = strings.Compare(a.Name, b.Name)
+	case SortByPrice:
+		switch {
+		case a.Price < b.Price:
+			c = -1
+		case a.Price > b.Price:
+			c = 1
+		}
+	}
+	if c == 0 {
+		c = a.ID - b.ID
+	}
+	if q.Desc {
+		return -c
+	}
+	return c
+}
+
+// afterCursor reports whether p comes after the cursor position.
+func (q ListQuery) afterCursor(p Product, c cursor) bool {
+	return q.compare(p, Product{ID: c.ID, Name: c.Name, Price: c.Price}) > 0
+}
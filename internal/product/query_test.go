@@ -0,0 +1,169 @@
+package product
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"test-backend/internal/database/dbtest"
+	"test-backend/internal/validation"
+)
+
+// testRepositories returns an empty repository of each backend.
+func testRepositories(t *testing.T) map[string]Repository {
+	return map[string]Repository{
+		"memory": NewInMemoryRepository(),
+		"sqlite": NewSQLiteRepository(dbtest.Open(t)),
+	}
+}
+
+// seed creates products with many ties on name and price, so paging has to
+// fall back on the ID to keep its place.
+func seed(t *testing.T, repo Repository) []Product {
+	t.Helper()
+	var products []Product
+	for i, p := range []Product{
+		{Name: "b", Price: 2}, {Name: "a", Price: 2}, {Name: "b", Price: 1},
+		{Name: "c", Price: 2}, {Name: "a", Price: 1}, {Name: "b", Price: 2},
+		{Name: "a", Price: 3}, {Name: "b", Price: 2}, {Name: "c", Price: 1},
+	} {
+		created, err := repo.Create(context.Background(), p)
+		if err != nil {
+			t.Fatalf("Create %d: %v", i, err)
+		}
+		products = append(products, created)
+	}
+	return products
+}
+
+func TestListCursorPaging(t *testing.T) {
+	ctx := context.Background()
+	for backend, repo := range testRepositories(t) {
+		products := seed(t, repo)
+		for _, sort := range []SortField{SortByID, SortByName, SortByPrice} {
+			for _, desc := range []bool{false, true} {
+				want := slices.Clone(products)
+				slices.SortFunc(want, func(a, b Product) int {
+					c := 0
+					switch sort {
+					case SortByName:
+						c = cmp.Compare(a.Name, b.Name)
+					case SortByPrice:
+						c = cmp.Compare(a.Price, b.Price)
+					}
+					if c == 0 {
+						c = cmp.Compare(a.ID, b.ID)
+					}
+					if desc {
+						return -c
+					}
+					return c
+				})
+
+				var got []Product
+				q := ListQuery{Limit: 2, Sort: sort, Desc: desc}
+				for pages := 0; ; pages++ {
+					if pages > len(products) {
+						t.Fatalf("%s sort=%s desc=%v: paging does not end", backend, sort, desc)
+					}
+					if err := q.Normalize(); err != nil {
+						t.Fatalf("%s sort=%s desc=%v: Normalize: %v", backend, sort, desc, err)
+					}
+					page, err := repo.List(ctx, q)
+					if err != nil {
+						t.Fatalf("%s sort=%s desc=%v: List: %v", backend, sort, desc, err)
+					}
+					if page.Total != len(products) {
+						t.Errorf("%s sort=%s desc=%v: Total = %d, want %d", backend, sort, desc, page.Total, len(products))
+					}
+					got = append(got, page.Items...)
+					if page.NextCursor == "" {
+						break
+					}
+					q.Cursor = page.NextCursor
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("%s sort=%s desc=%v: pages = %v, want %v", backend, sort, desc, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestListNameFilter(t *testing.T) {
+	ctx := context.Background()
+	for backend, repo := range testRepositories(t) {
+		for _, name := range []string{"Äpfel", "grüne ÄPFEL", "Apfelsaft", "Birne"} {
+			if _, err := repo.Create(ctx, Product{Name: name, Price: 1}); err != nil {
+				t.Fatalf("%s: Create: %v", backend, err)
+			}
+		}
+		tests := []struct {
+			filter string
+			want   []string
+		}{
+			{"äpfel", []string{"Äpfel", "grüne ÄPFEL"}},
+			{"ÄPF", []string{"Äpfel", "grüne ÄPFEL"}},
+			{"APFEL", []string{"Apfelsaft"}},
+			{"GRÜN", []string{"grüne ÄPFEL"}},
+			{"%", nil},
+		}
+		for _, tt := range tests {
+			q := ListQuery{Name: tt.filter}
+			if err := q.Normalize(); err != nil {
+				t.Fatalf("Normalize: %v", err)
+			}
+			page, err := repo.List(ctx, q)
+			if err != nil {
+				t.Fatalf("%s: List: %v", backend, err)
+			}
+			var got []string
+			for _, p := range page.Items {
+				got = append(got, p.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%s: name=%q matched %q, want %q", backend, tt.filter, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestGetProductsRejectsBadCursors(t *testing.T) {
+	repo := NewInMemoryRepository()
+	seed(t, repo)
+	r := newTestRouter(repo)
+
+	rec, _ := serve(r, http.MethodGet, "/products?limit=2&sort=name", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first page: status %d", rec.Code)
+	}
+	next := decodePage(t, rec.Body.Bytes()).NextCursor
+	raw, err := base64.RawURLEncoding.DecodeString(next)
+	if err != nil {
+		t.Fatalf("decode cursor: %v", err)
+	}
+	tampered := base64.RawURLEncoding.EncodeToString(append(raw[:len(raw)-1:len(raw)-1], ','))
+
+	tests := []struct {
+		name   string
+		query  string
+		reason string
+	}{
+		{"not base64", "sort=name&cursor=" + url.QueryEscape("!!!"), "is invalid"},
+		{"tampered JSON", "sort=name&cursor=" + tampered, "is invalid"},
+		{"no ID", "sort=name&cursor=" + base64.RawURLEncoding.EncodeToString([]byte(`{"s":"name","n":"b"}`)), "is invalid"},
+		{"other sort field", "sort=price&cursor=" + next, "was issued for a different sort order"},
+		{"other direction", "sort=-name&cursor=" + next, "was issued for a different sort order"},
+	}
+	for _, tt := range tests {
+		rec, p := serve(r, http.MethodGet, "/products?"+tt.query, "")
+		want := validation.Errors{{Field: "cursor", Reason: tt.reason}}
+		if rec.Code != http.StatusUnprocessableEntity || !slices.Equal(p.Errors, want) {
+			t.Errorf("%s: status %d errors %v, want 422 with %v", tt.name, rec.Code, p.Errors, want)
+		}
+	}
+}
@@ -1,18 +1,30 @@
 package product
 
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
 // Repository defines methods for product data access.
 type Repository interface {
-	GetAll() []Product
-	GetByID(id int) (Product, bool)
-	Create(product Product) Product
-	Update(id int, product Product) (Product, bool)
-	Delete(id int) bool
+	// List returns the page of products selected by a normalized query.
+	List(ctx context.Context, q ListQuery) (Page, error)
+	GetByID(ctx context.Context, id int) (Product, error)
+	Create(ctx context.Context, product Product) (Product, error)
+	Update(ctx context.Context, id int, product Product) (Product, error)
+	Delete(ctx context.Context, id int) error
+	// Ping reports whether the repository can currently serve requests.
+	Ping(ctx context.Context) error
 }
 
 // InMemoryRepository is an in-memory implementation of Repository.
+// It is safe for concurrent use.
 type InMemoryRepository struct {
+	mu     sync.RWMutex
 	data   map[int]Product
-	lastID int
+	lastID atomic.Int64
 }
 
 // NewInMemoryRepository creates a new in-memory repository.
@@ -20,39 +32,70 @@ func NewInMemoryRepository() *InMemoryRepository {
 	return &InMemoryRepository{data: make(map[int]Product)}
 }
 
-func (r *InMemoryRepository) GetAll() []Product {
-	products := make([]Product, 0, len(r.data))
+func (r *InMemoryRepository) List(ctx context.Context, q ListQuery) (Page, error) {
+	r.mu.RLock()
+	matched := make([]Product, 0, len(r.data))
 	for _, p := range r.data {
-		products = append(products, p)
+		if q.matches(p) {
+			matched = append(matched, p)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(matched, func(i, j int) bool { return q.compare(matched[i], matched[j]) < 0 })
+
+	start := q.Offset
+	if c, ok := q.cursor(); ok {
+		start = sort.Search(len(matched), func(i int) bool { return q.afterCursor(matched[i], c) })
+	}
+	start = min(start, len(matched))
+	end := min(start+q.Limit, len(matched))
+
+	page := Page{Items: matched[start:end], Total: len(matched), Limit: q.Limit, Offset: q.Offset}
+	if end < len(matched) && end > start {
+		page.NextCursor = q.cursorAfter(matched[end-1])
 	}
-	return products
+	return page, nil
 }
 
-func (r *InMemoryRepository) GetByID(id int) (Product, bool) {
+func (r *InMemoryRepository) GetByID(ctx context.Context, id int) (Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	p, ok := r.data[id]
-	return p, ok
+	if !ok {
+		return Product{}, ErrNotFound
+	}
+	return p, nil
 }
 
-func (r *InMemoryRepository) Create(product Product) Product {
-	r.lastID++
-	product.ID = r.lastID
+func (r *InMemoryRepository) Create(ctx context.Context, product Product) (Product, error) {
+	product.ID = int(r.lastID.Add(1))
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.data[product.ID] = product
-	return product
+	return product, nil
 }
 
-func (r *InMemoryRepository) Update(id int, product Product) (Product, bool) {
+func (r *InMemoryRepository) Update(ctx context.Context, id int, product Product) (Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if _, ok := r.data[id]; !ok {
-		return Product{}, false
+		return Product{}, ErrNotFound
 	}
 	product.ID = id
 	r.data[id] = product
-	return product, true
+	return product, nil
 }
 
-func (r *InMemoryRepository) Delete(id int) bool {
+func (r *InMemoryRepository) Delete(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if _, ok := r.data[id]; !ok {
-		return false
+		return ErrNotFound
 	}
 	delete(r.data, id)
-	return true
+	return nil
+}
+
+func (r *InMemoryRepository) Ping(ctx context.Context) error {
+	return nil
 }
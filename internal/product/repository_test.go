@@ -0,0 +1,60 @@
+package product
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestInMemoryRepositoryConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepository()
+	const workers = 50
+
+	ids := make(chan int, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			created, err := repo.Create(ctx, Product{Name: "product", Price: float64(i)})
+			if err != nil {
+				t.Errorf("Create: %v", err)
+				return
+			}
+			ids <- created.ID
+
+			if _, err := repo.GetByID(ctx, created.ID); err != nil {
+				t.Errorf("GetByID(%d): %v", created.ID, err)
+			}
+			if _, err := repo.List(ctx, ListQuery{Limit: MaxLimit, Sort: SortByPrice, Name: "product"}); err != nil {
+				t.Errorf("List: %v", err)
+			}
+			if _, err := repo.Update(ctx, created.ID, Product{Name: "updated", Price: 1}); err != nil {
+				t.Errorf("Update(%d): %v", created.ID, err)
+			}
+			if i%2 == 0 {
+				if err := repo.Delete(ctx, created.ID); err != nil {
+					t.Errorf("Delete(%d): %v", created.ID, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[int]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate id %d", id)
+		}
+		seen[id] = true
+	}
+	page, err := repo.List(ctx, ListQuery{Limit: MaxLimit, Sort: SortByID})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if page.Total != workers/2 {
+		t.Fatalf("List() found %d products, want %d", page.Total, workers/2)
+	}
+}
@@ -1,12 +1,14 @@
 package product
 
+import "context"
+
 // Service defines business logic for products.
 type Service interface {
-	GetAll() []Product
-	GetByID(id int) (Product, bool)
-	Create(product Product) Product
-	Update(id int, product Product) (Product, bool)
-	Delete(id int) bool
+	List(ctx context.Context, q ListQuery) (Page, error)
+	GetByID(ctx context.Context, id int) (Product, error)
+	Create(ctx context.Context, product Product) (Product, error)
+	Update(ctx context.Context, id int, product Product) (Product, error)
+	Delete(ctx context.Context, id int) error
 }
 
 type service struct {
@@ -18,22 +20,31 @@ func NewService(r Repository) Service {
 	return &service{repo: r}
 }
 
-func (s *service) GetAll() []Product {
-	return s.repo.GetAll()
+func (s *service) List(ctx context.Context, q ListQuery) (Page, error) {
+	if err := q.Normalize(); err != nil {
+		return Page{}, err
+	}
+	return s.repo.List(ctx, q)
 }
 
-func (s *service) GetByID(id int) (Product, bool) {
-	return s.repo.GetByID(id)
+func (s *service) GetByID(ctx context.Context, id int) (Product, error) {
+	return s.repo.GetByID(ctx, id)
 }
 
-func (s *service) Create(product Product) Product {
-	return s.repo.Create(product)
+func (s *service) Create(ctx context.Context, product Product) (Product, error) {
+	if err := product.Validate(); err != nil {
+		return Product{}, err
+	}
+	return s.repo.Create(ctx, product)
 }
 
-func (s *service) Update(id int, product Product) (Product, bool) {
-	return s.repo.Update(id, product)
+func (s *service) Update(ctx context.Context, id int, product Product) (Product, error) {
+	if err := product.Validate(); err != nil {
+		return Product{}, err
+	}
+	return s.repo.Update(ctx, id, product)
 }
 
-func (s *service) Delete(id int) bool {
-	return s.repo.Delete(id)
+func (s *service) Delete(ctx context.Context, id int) error {
+	return s.repo.Delete(ctx, id)
 }
@@ -0,0 +1,172 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SQLiteRepository is a SQLite implementation of Repository.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLite repository.
+// The schema is managed by the migrations package.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// sortColumns maps sort fields to their columns.
+var sortColumns = map[SortField]string{
+	SortByID:    "id",
+	SortByName:  "name",
+	SortByPrice: "price",
+}
+
+func (r *SQLiteRepository) List(ctx context.Context, q ListQuery) (Page, error) {
+	var where []string
+	var args []any
+	if q.MinPrice != nil {
+		where = append(where, "price >= ?")
+		args = append(args, *q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		where = append(where, "price <= ?")
+		args = append(args, *q.MaxPrice)
+	}
+	if q.Name != "" {
+		where = append(where, "instr(unicode_lower(name), ?) > 0")
+		args = append(args, strings.ToLower(q.Name))
+	}
+
+	page := Page{Items: []Product{}, Limit: q.Limit, Offset: q.Offset}
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM products"+whereClause(where), args...).
+		Scan(&page.Total); err != nil {
+		return Page{}, err
+	}
+
+	col, ok := sortColumns[q.Sort]
+	if !ok {
+		col = "id"
+	}
+	dir, op := "ASC", ">"
+	if q.Desc {
+		dir, op = "DESC", "<"
+	}
+	if c, ok := q.cursor(); ok {
+		switch q.Sort {
+		case SortByName:
+			where = append(where, "(name, id) "+op+" (?, ?)")
+			args = append(args, c.Name, c.ID)
+		case SortByPrice:
+			where = append(where, "(price, id) "+op+" (?, ?)")
+			args = append(args, c.Price, c.ID)
+		default:
+			where = append(where, "id "+op+" ?")
+			args = append(args, c.ID)
+		}
+	}
+	query := "SELECT id, name, price FROM products" + whereClause(where)
+	if col == "id" {
+		query += fmt.Sprintf(" ORDER BY id %s", dir)
+	} else {
+		query += fmt.Sprintf(" ORDER BY %s %s, id %s", col, dir, dir)
+	}
+	// Fetch one extra row to learn whether another page follows.
+	query += " LIMIT ? OFFSET ?"
+	args = append(args, q.Limit+1, q.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return Page{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p Product
+		if err := rows.Scan(&p.ID, &p.Name, &p.Price); err != nil {
+			return Page{}, err
+		}
+		page.Items = append(page.Items, p)
+	}
+	if err := rows.Err(); err != nil {
+		return Page{}, err
+	}
+	if len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		page.NextCursor = q.cursorAfter(page.Items[len(page.Items)-1])
+	}
+	return page, nil
+}
+
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+func (r *SQLiteRepository) GetByID(ctx context.Context, id int) (Product, error) {
+	var p Product
+	err := r.db.QueryRowContext(ctx, `SELECT id, name, price FROM products WHERE id = ?`, id).
+		Scan(&p.ID, &p.Name, &p.Price)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Product{}, ErrNotFound
+	}
+	if err != nil {
+		return Product{}, err
+	}
+	return p, nil
+}
+
+func (r *SQLiteRepository) Create(ctx context.Context, product Product) (Product, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO products (name, price) VALUES (?, ?)`, product.Name, product.Price)
+	if err != nil {
+		return Product{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Product{}, err
+	}
+	product.ID = int(id)
+	return product, nil
+}
+
+func (r *SQLiteRepository) Update(ctx context.Context, id int, product Product) (Product, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE products SET name = ?, price = ? WHERE id = ?`,
+		product.Name, product.Price, id)
+	if err != nil {
+		return Product{}, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return Product{}, err
+	} else if n == 0 {
+		return Product{}, ErrNotFound
+	}
+	product.ID = id
+	return product, nil
+}
+
+func (r *SQLiteRepository) Delete(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM products WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) Ping(ctx context.Context) error {
+	var one int
+	err := r.db.QueryRowContext(ctx, `SELECT 1 FROM products LIMIT 1`).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
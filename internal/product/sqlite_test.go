@@ -0,0 +1,89 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"test-backend/internal/database/dbtest"
+)
+
+func TestSQLiteRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSQLiteRepository(dbtest.Open(t))
+
+	created, err := repo.Create(ctx, Product{Name: "Lamp", Price: 19.5})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.ID == 0 {
+		t.Fatalf("Create: got ID 0")
+	}
+	if got, err := repo.GetByID(ctx, created.ID); err != nil || got != created {
+		t.Errorf("GetByID = %+v, %v, want %+v", got, err, created)
+	}
+
+	updated, err := repo.Update(ctx, created.ID, Product{Name: "Desk lamp", Price: 24})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	want := Product{ID: created.ID, Name: "Desk lamp", Price: 24}
+	if updated != want {
+		t.Errorf("Update = %+v, want %+v", updated, want)
+	}
+	if got, _ := repo.GetByID(ctx, created.ID); got != want {
+		t.Errorf("GetByID after Update = %+v, want %+v", got, want)
+	}
+
+	if _, err := repo.Create(ctx, Product{Name: "Chair", Price: 80}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	page, err := repo.List(ctx, ListQuery{Limit: 10, Sort: SortByPrice, Desc: true})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if page.Total != 2 || len(page.Items) != 2 || page.Items[0].Name != "Chair" || page.Items[1].Name != "Desk lamp" {
+		t.Errorf("List = %+v, want Chair then Desk lamp", page)
+	}
+
+	if err := repo.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSQLiteRepositoryNotFound(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSQLiteRepository(dbtest.Open(t))
+
+	_, getErr := repo.GetByID(ctx, 999)
+	_, updateErr := repo.Update(ctx, 999, Product{Name: "Lamp"})
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"GetByID", getErr},
+		{"Update", updateErr},
+		{"Delete", repo.Delete(ctx, 999)},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, ErrNotFound) {
+			t.Errorf("%s: err = %v, want ErrNotFound", tt.name, tt.err)
+		}
+	}
+}
+
+func TestSQLiteRepositoryPing(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	repo := NewSQLiteRepository(db)
+	if err := repo.Ping(ctx); err != nil {
+		t.Fatalf("Ping on an empty table: %v", err)
+	}
+	db.Close()
+	if err := repo.Ping(ctx); err == nil {
+		t.Fatalf("Ping on a closed database: got nil error")
+	}
+}
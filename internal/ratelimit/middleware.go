@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"test-backend/internal/auth"
+	"test-backend/internal/logging"
+	"test-backend/internal/problem"
+)
+
+// KeyFunc identifies the client a request counts against. It returns false if
+// the request has no such client, in which case it is not limited.
+type KeyFunc func(c *gin.Context) (string, bool)
+
+// ByIP keys requests by client IP address.
+func ByIP(c *gin.Context) (string, bool) {
+	return "ip:" + c.ClientIP(), true
+}
+
+// ByUser keys requests by the authenticated user. It must run after auth.JWTMiddleware.
+func ByUser(c *gin.Context) (string, bool) {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return "", false
+	}
+	return "user:" + strconv.Itoa(principal.UserID), true
+}
+
+// maxKeyBodySize bounds how much of a request body ByJSONField reads.
+const maxKeyBodySize = 1 << 20
+
+// ByJSONField keys requests by a string field of their JSON body, compared
+// case-insensitively, such as the email of a login attempt. The body is left
+// intact for the handler.
+func ByJSONField(field string) KeyFunc {
+	return func(c *gin.Context) (string, bool) {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxKeyBodySize))
+		c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+		if err != nil {
+			return "", false
+		}
+		var fields map[string]any
+		if json.Unmarshal(body, &fields) != nil {
+			return "", false
+		}
+		value, _ := fields[field].(string)
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			return "", false
+		}
+		return field + ":" + value, true
+	}
+}
+
+// Middleware rejects requests over limit with 429 Too Many Requests. Requests
+// are counted per key in a bucket namespaced by name, so route groups sharing
+// a store have separate budgets. Every limited response carries the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers, and
+// rejections also carry Retry-After. If the store fails the request is let
+// through rather than locking every client out.
+func Middleware(store Store, name string, limit Limit, key KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k, ok := key(c)
+		if !ok {
+			c.Next()
+			return
+		}
+		ctx := c.Request.Context()
+		res, err := store.Take(ctx, name+":"+k, limit)
+		if err != nil {
+			logging.FromContext(ctx).Error("rate limit store failed", "limiter", name, "error", err)
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			retry := ceilSeconds(res.RetryAfter)
+			h.Set("Retry-After", strconv.Itoa(retry))
+			logging.FromContext(ctx).Warn("rate limit exceeded", "limiter", name)
+			problem.Abort(c, http.StatusTooManyRequests, fmt.Sprintf("rate limit exceeded, retry in %d seconds", retry))
+			return
+		}
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds d up to whole seconds, as the rate limit headers require.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddlewareHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	byClient := func(c *gin.Context) (string, bool) { return "client", true }
+
+	tests := []struct {
+		name       string
+		status     int
+		remaining  string
+		reset      string
+		retryAfter string
+	}{
+		{"first request", http.StatusOK, "1", "30", ""},
+		{"second request", http.StatusOK, "0", "60", ""},
+		{"over the limit", http.StatusTooManyRequests, "0", "60", "30"},
+	}
+	for backend, store := range testStores(t, &now) {
+		r := gin.New()
+		r.GET("/", Middleware(store, "test", Limit{Requests: 2, Per: time.Minute}, byClient), func(c *gin.Context) { c.Status(http.StatusOK) })
+		for _, tt := range tests {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			h := rec.Header()
+			if rec.Code != tt.status {
+				t.Errorf("%s: %s: status %d, want %d", backend, tt.name, rec.Code, tt.status)
+			}
+			if got := h.Get("RateLimit-Limit"); got != "2" {
+				t.Errorf("%s: %s: RateLimit-Limit = %q, want %q", backend, tt.name, got, "2")
+			}
+			if got := h.Get("RateLimit-Remaining"); got != tt.remaining {
+				t.Errorf("%s: %s: RateLimit-Remaining = %q, want %q", backend, tt.name, got, tt.remaining)
+			}
+			if got := h.Get("RateLimit-Reset"); got != tt.reset {
+				t.Errorf("%s: %s: RateLimit-Reset = %q, want %q", backend, tt.name, got, tt.reset)
+			}
+			if got := h.Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("%s: %s: Retry-After = %q, want %q", backend, tt.name, got, tt.retryAfter)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Package ratelimit throttles clients with token buckets.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests per Per on average, with bursts of up to Burst requests.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	// Burst is the bucket capacity. It defaults to Requests.
+	Burst int
+}
+
+// capacity returns the bucket size.
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// rate returns the refill rate in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	// Limit is the bucket capacity and Remaining the whole tokens left in it.
+	Limit     int
+	Remaining int
+	// RetryAfter is how long to wait for the next token when not Allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps token buckets.
+type Store interface {
+	// Take removes a token from the bucket for key under limit, if one is left.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucket is the state of one token bucket.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take refills b for the time elapsed until now and removes a token if one is
+// left. A zero bucket is treated as full.
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	capacity, rate := limit.capacity(), limit.rate()
+	if b.updated.IsZero() {
+		b.tokens = capacity
+	} else if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+	}
+	b.updated = now
+
+	res := Result{Limit: int(capacity)}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((capacity - b.tokens) / rate)
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// InMemoryStore is an in-memory implementation of Store for a single instance.
+// It is safe for concurrent use.
+type InMemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// pruneInterval is how often idle buckets are dropped.
+const pruneInterval = time.Minute
+
+// NewInMemoryStore creates a new in-memory store.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (s *InMemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.Sub(s.lastPrune) > pruneInterval {
+		s.prune(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{}
+		s.buckets[key] = b
+	}
+	return b.take(limit, now), nil
+}
+
+// prune drops buckets idle for longer than maxIdle, which have refilled for
+// any limit that refills within that time and so behave like new ones.
+// The caller must hold s.mu.
+func (s *InMemoryStore) prune(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) > maxIdle {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastPrune = now
+}
+
+// maxIdle is how long an untouched bucket is kept. Limits should refill
+// completely within it.
+const maxIdle = 24 * time.Hour
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"test-backend/internal/database/dbtest"
+)
+
+// testStores returns an empty store of each backend whose clock reads *now.
+func testStores(t *testing.T, now *time.Time) map[string]Store {
+	clock := func() time.Time { return *now }
+	memory := NewInMemoryStore()
+	memory.now = clock
+	sqlite := NewSQLiteStore(dbtest.Open(t))
+	sqlite.now = clock
+	return map[string]Store{"memory": memory, "sqlite": sqlite}
+}
+
+// within reports whether got is within a millisecond of want, allowing for
+// floating point rounding in the bucket arithmetic.
+func within(got, want time.Duration) bool {
+	d := got - want
+	return d > -time.Millisecond && d < time.Millisecond
+}
+
+func TestStoreTake(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := t0
+
+	// Three requests a minute: one token every 20 seconds.
+	limit := Limit{Requests: 3, Per: time.Minute}
+	tests := []struct {
+		name       string
+		at         time.Duration
+		key        string
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+		reset      time.Duration
+	}{
+		{"first request", 0, "a", true, 2, 0, 20 * time.Second},
+		{"second request", 0, "a", true, 1, 0, 40 * time.Second},
+		{"last token", 0, "a", true, 0, 0, time.Minute},
+		{"empty bucket", 0, "a", false, 0, 20 * time.Second, time.Minute},
+		{"other keys have their own bucket", 0, "b", true, 2, 0, 20 * time.Second},
+		{"half a token refilled", 10 * time.Second, "a", false, 0, 10 * time.Second, 50 * time.Second},
+		{"one token refilled", 20 * time.Second, "a", true, 0, 0, time.Minute},
+		{"refill stops at capacity", time.Hour, "a", true, 2, 0, 20 * time.Second},
+	}
+	for backend, s := range testStores(t, &now) {
+		for _, tt := range tests {
+			now = t0.Add(tt.at)
+			res, err := s.Take(context.Background(), tt.key, limit)
+			if err != nil {
+				t.Fatalf("%s: %s: Take: %v", backend, tt.name, err)
+			}
+			if res.Allowed != tt.allowed || res.Remaining != tt.remaining || res.Limit != 3 {
+				t.Errorf("%s: %s: got allowed=%v remaining=%d limit=%d, want allowed=%v remaining=%d limit=3",
+					backend, tt.name, res.Allowed, res.Remaining, res.Limit, tt.allowed, tt.remaining)
+			}
+			if !within(res.RetryAfter, tt.retryAfter) {
+				t.Errorf("%s: %s: RetryAfter = %s, want %s", backend, tt.name, res.RetryAfter, tt.retryAfter)
+			}
+			if !within(res.Reset, tt.reset) {
+				t.Errorf("%s: %s: Reset = %s, want %s", backend, tt.name, res.Reset, tt.reset)
+			}
+		}
+	}
+}
+
+func TestStoreTakeBurst(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// One token a second, but up to five at once.
+	limit := Limit{Requests: 60, Per: time.Minute, Burst: 5}
+	for backend, s := range testStores(t, &now) {
+		for i := 0; i < 5; i++ {
+			res, err := s.Take(context.Background(), "a", limit)
+			if err != nil {
+				t.Fatalf("%s: Take: %v", backend, err)
+			}
+			if !res.Allowed || res.Limit != 5 || res.Remaining != 4-i {
+				t.Fatalf("%s: request %d: got %+v, want allowed with %d remaining of 5", backend, i+1, res, 4-i)
+			}
+		}
+		res, err := s.Take(context.Background(), "a", limit)
+		if err != nil {
+			t.Fatalf("%s: Take: %v", backend, err)
+		}
+		if res.Allowed || !within(res.RetryAfter, time.Second) || !within(res.Reset, 5*time.Second) {
+			t.Fatalf("%s: request over the burst: got %+v, want denied with RetryAfter 1s and Reset 5s", backend, res)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// SQLiteStore is a SQLite implementation of Store. Instances sharing the
+// database file share their buckets.
+type SQLiteStore struct {
+	db        *sql.DB
+	mu        sync.Mutex
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewSQLiteStore creates a new SQLite store.
+// The schema is managed by the migrations package.
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{db: db, now: time.Now}
+}
+
+func (s *SQLiteStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := s.now().UTC()
+	if err := s.maybePrune(ctx, now); err != nil {
+		return Result{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+	// Write first so the transaction takes the write lock before reading;
+	// upgrading a read transaction can fail when another process is writing.
+	if _, err := tx.ExecContext(ctx, `UPDATE rate_limit_buckets SET tokens = tokens WHERE key = ?`, key); err != nil {
+		return Result{}, err
+	}
+
+	var b bucket
+	err = tx.QueryRowContext(ctx, `SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = ?`, key).
+		Scan(&b.tokens, &b.updated)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Result{}, err
+	}
+	res := b.take(limit, now)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET tokens = excluded.tokens, updated_at = excluded.updated_at`,
+		key, b.tokens, b.updated)
+	if err != nil {
+		return Result{}, err
+	}
+	return res, tx.Commit()
+}
+
+// maybePrune deletes idle buckets at most once per pruneInterval.
+func (s *SQLiteStore) maybePrune(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	due := now.Sub(s.lastPrune) > pruneInterval
+	if due {
+		s.lastPrune = now
+	}
+	s.mu.Unlock()
+	if !due {
+		return nil
+	}
+	_, err := s.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < ?`, now.Add(-maxIdle))
+	return err
+}
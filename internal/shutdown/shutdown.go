@@ -0,0 +1,47 @@
+// Package shutdown collects the cleanup work to run when the server stops.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Hooks is an ordered list of cleanup functions. The zero value is ready to use
+// and it is safe for concurrent use.
+type Hooks struct {
+	mu    sync.Mutex
+	hooks []hook
+}
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Add registers fn to run on shutdown under name, which is used in errors.
+func (h *Hooks) Add(name string, fn func(ctx context.Context) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks = append(h.hooks, hook{name: name, fn: fn})
+}
+
+// Run calls every hook in reverse order of registration, so resources are
+// released after whatever was started later and depends on them. It runs all
+// hooks even if some fail, and returns their errors joined. Hooks should give up
+// when ctx is done.
+func (h *Hooks) Run(ctx context.Context) error {
+	h.mu.Lock()
+	hooks := h.hooks
+	h.hooks = nil
+	h.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hooks[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
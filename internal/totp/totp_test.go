@@ -0,0 +1,79 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 seed of the RFC 6238 test vectors.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCodeRFC6238(t *testing.T) {
+	// RFC 6238 Appendix B gives 8 digit SHA1 codes; ours are their last 6 digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := Code(rfcSecret, Step(time.Unix(tt.unix, 0))); got != tt.want {
+			t.Errorf("T=%d: Code = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+
+	tests := []struct {
+		name string
+		code string
+		skew int
+		step int64
+		ok   bool
+	}{
+		{"current step", Code(rfcSecret, step), 0, step, true},
+		{"previous step within skew", Code(rfcSecret, step-1), 1, step - 1, true},
+		{"next step within skew", Code(rfcSecret, step+1), 1, step + 1, true},
+		{"previous step without skew", Code(rfcSecret, step-1), 0, 0, false},
+		{"outside skew", Code(rfcSecret, step-2), 1, 0, false},
+		{"wrong length", "50471", 1, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := Validate(rfcSecret, tt.code, now, tt.skew)
+		if ok != tt.ok || got != tt.step {
+			t.Errorf("%s: Validate = %d, %v, want %d, %v", tt.name, got, ok, tt.step, tt.ok)
+		}
+	}
+}
+
+func TestURI(t *testing.T) {
+	u, err := url.Parse(URI("Example API", "alice@example.com", rfcSecret))
+	if err != nil {
+		t.Fatalf("parse URI: %v", err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Example API:alice@example.com" {
+		t.Errorf("URI = %s, want otpauth://totp/Example API:alice@example.com", u)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"secret":    "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"issuer":    "Example API",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("%s = %q, want %q", k, q.Get(k), v)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"test-backend/internal/product"
+)
+
+// ProductService wraps s so every call is recorded as a span.
+func ProductService(s product.Service) product.Service {
+	return &productService{next: s}
+}
+
+type productService struct {
+	next product.Service
+}
+
+func (s *productService) List(ctx context.Context, q product.ListQuery) (product.Page, error) {
+	ctx, span := start(ctx, "product.Service/List", listAttrs(q.Limit, q.Offset)...)
+	page, err := s.next.List(ctx, q)
+	end(span, err)
+	return page, err
+}
+
+func (s *productService) GetByID(ctx context.Context, id int) (product.Product, error) {
+	ctx, span := start(ctx, "product.Service/GetByID", attribute.Int("product.id", id))
+	p, err := s.next.GetByID(ctx, id)
+	end(span, err)
+	return p, err
+}
+
+func (s *productService) Create(ctx context.Context, p product.Product) (product.Product, error) {
+	ctx, span := start(ctx, "product.Service/Create")
+	created, err := s.next.Create(ctx, p)
+	if err == nil {
+		span.SetAttributes(attribute.Int("product.id", created.ID))
+	}
+	end(span, err)
+	return created, err
+}
+
+func (s *productService) Update(ctx context.Context, id int, p product.Product) (product.Product, error) {
+	ctx, span := start(ctx, "product.Service/Update", attribute.Int("product.id", id))
+	updated, err := s.next.Update(ctx, id, p)
+	end(span, err)
+	return updated, err
+}
+
+func (s *productService) Delete(ctx context.Context, id int) error {
+	ctx, span := start(ctx, "product.Service/Delete", attribute.Int("product.id", id))
+	err := s.next.Delete(ctx, id)
+	end(span, err)
+	return err
+}
+
+// ProductRepository wraps r so every call is recorded as a span.
+func ProductRepository(r product.Repository) product.Repository {
+	return &productRepository{next: r}
+}
+
+type productRepository struct {
+	next product.Repository
+}
+
+func (r *productRepository) List(ctx context.Context, q product.ListQuery) (product.Page, error) {
+	ctx, span := start(ctx, "product.Repository/List", listAttrs(q.Limit, q.Offset)...)
+	page, err := r.next.List(ctx, q)
+	if err == nil {
+		span.SetAttributes(attribute.Int("list.total", page.Total))
+	}
+	end(span, err)
+	return page, err
+}
+
+func (r *productRepository) GetByID(ctx context.Context, id int) (product.Product, error) {
+	ctx, span := start(ctx, "product.Repository/GetByID", attribute.Int("product.id", id))
+	p, err := r.next.GetByID(ctx, id)
+	end(span, err)
+	return p, err
+}
+
+func (r *productRepository) Create(ctx context.Context, p product.Product) (product.Product, error) {
+	ctx, span := start(ctx, "product.Repository/Create")
+	created, err := r.next.Create(ctx, p)
+	end(span, err)
+	return created, err
+}
+
+func (r *productRepository) Update(ctx context.Context, id int, p product.Product) (product.Product, error) {
+	ctx, span := start(ctx, "product.Repository/Update", attribute.Int("product.id", id))
+	updated, err := r.next.Update(ctx, id, p)
+	end(span, err)
+	return updated, err
+}
+
+func (r *productRepository) Delete(ctx context.Context, id int) error {
+	ctx, span := start(ctx, "product.Repository/Delete", attribute.Int("product.id", id))
+	err := r.next.Delete(ctx, id)
+	end(span, err)
+	return err
+}
+
+// Ping is not traced: health checks call it often and outside any request.
+func (r *productRepository) Ping(ctx context.Context) error {
+	return r.next.Ping(ctx)
+}
@@ -0,0 +1,209 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"test-backend/internal/user"
+)
+
+// UserService wraps s so every call is recorded as a span.
+func UserService(s user.Service) user.Service {
+	return &userService{next: s}
+}
+
+type userService struct {
+	next user.Service
+}
+
+func (s *userService) List(ctx context.Context, q user.ListQuery) (user.Page, error) {
+	ctx, span := start(ctx, "user.Service/List", listAttrs(q.Limit, q.Offset)...)
+	page, err := s.next.List(ctx, q)
+	end(span, err)
+	return page, err
+}
+
+func (s *userService) GetByID(ctx context.Context, id int) (user.User, error) {
+	ctx, span := start(ctx, "user.Service/GetByID", attribute.Int("user.id", id))
+	u, err := s.next.GetByID(ctx, id)
+	end(span, err)
+	return u, err
+}
+
+func (s *userService) GetByEmail(ctx context.Context, email string) (user.User, error) {
+	ctx, span := start(ctx, "user.Service/GetByEmail")
+	u, err := s.next.GetByEmail(ctx, email)
+	end(span, err)
+	return u, err
+}
+
+func (s *userService) Create(ctx context.Context, u user.User) (user.User, error) {
+	ctx, span := start(ctx, "user.Service/Create")
+	created, err := s.next.Create(ctx, u)
+	if err == nil {
+		span.SetAttributes(attribute.Int("user.id", created.ID))
+	}
+	end(span, err)
+	return created, err
+}
+
+func (s *userService) Update(ctx context.Context, id int, u user.User) (user.User, error) {
+	ctx, span := start(ctx, "user.Service/Update", attribute.Int("user.id", id))
+	updated, err := s.next.Update(ctx, id, u)
+	end(span, err)
+	return updated, err
+}
+
+func (s *userService) UpdateProfile(ctx context.Context, id int, u user.User, currentPassword string) (user.User, error) {
+	ctx, span := start(ctx, "user.Service/UpdateProfile", attribute.Int("user.id", id))
+	updated, err := s.next.UpdateProfile(ctx, id, u, currentPassword)
+	end(span, err)
+	return updated, err
+}
+
+func (s *userService) Delete(ctx context.Context, id int) error {
+	ctx, span := start(ctx, "user.Service/Delete", attribute.Int("user.id", id))
+	err := s.next.Delete(ctx, id)
+	end(span, err)
+	return err
+}
+
+func (s *userService) Authenticate(ctx context.Context, email, password, ip string) (user.User, error) {
+	ctx, span := start(ctx, "user.Service/Authenticate")
+	u, err := s.next.Authenticate(ctx, email, password, ip)
+	end(span, err)
+	return u, err
+}
+
+func (s *userService) AuthenticateSecondFactor(ctx context.Context, id int, code, ip string) (user.User, error) {
+	ctx, span := start(ctx, "user.Service/AuthenticateSecondFactor", attribute.Int("user.id", id))
+	u, err := s.next.AuthenticateSecondFactor(ctx, id, code, ip)
+	end(span, err)
+	return u, err
+}
+
+func (s *userService) TwoFactorEnabled(ctx context.Context, id int) (bool, error) {
+	ctx, span := start(ctx, "user.Service/TwoFactorEnabled", attribute.Int("user.id", id))
+	enabled, err := s.next.TwoFactorEnabled(ctx, id)
+	end(span, err)
+	return enabled, err
+}
+
+func (s *userService) EnrollTOTP(ctx context.Context, id int) ([]byte, error) {
+	ctx, span := start(ctx, "user.Service/EnrollTOTP", attribute.Int("user.id", id))
+	secret, err := s.next.EnrollTOTP(ctx, id)
+	end(span, err)
+	return secret, err
+}
+
+func (s *userService) ConfirmTOTP(ctx context.Context, id int, code string) ([]string, error) {
+	ctx, span := start(ctx, "user.Service/ConfirmTOTP", attribute.Int("user.id", id))
+	codes, err := s.next.ConfirmTOTP(ctx, id, code)
+	end(span, err)
+	return codes, err
+}
+
+func (s *userService) DisableTOTP(ctx context.Context, id int, code string) error {
+	ctx, span := start(ctx, "user.Service/DisableTOTP", attribute.Int("user.id", id))
+	err := s.next.DisableTOTP(ctx, id, code)
+	end(span, err)
+	return err
+}
+
+func (s *userService) Lockouts(ctx context.Context) ([]user.Lockout, error) {
+	ctx, span := start(ctx, "user.Service/Lockouts")
+	lockouts, err := s.next.Lockouts(ctx)
+	end(span, err)
+	return lockouts, err
+}
+
+func (s *userService) ClearLockout(ctx context.Context, kind user.LockoutKind, key string) error {
+	ctx, span := start(ctx, "user.Service/ClearLockout", attribute.String("lockout.kind", string(kind)))
+	err := s.next.ClearLockout(ctx, kind, key)
+	end(span, err)
+	return err
+}
+
+func (s *userService) VerifyEmail(ctx context.Context, id int, email string) (user.User, error) {
+	ctx, span := start(ctx, "user.Service/VerifyEmail", attribute.Int("user.id", id))
+	u, err := s.next.VerifyEmail(ctx, id, email)
+	end(span, err)
+	return u, err
+}
+
+func (s *userService) EnsureAdmin(ctx context.Context, email, password string) (user.User, error) {
+	ctx, span := start(ctx, "user.Service/EnsureAdmin")
+	u, err := s.next.EnsureAdmin(ctx, email, password)
+	end(span, err)
+	return u, err
+}
+
+// UserRepository wraps r so every call is recorded as a span.
+func UserRepository(r user.Repository) user.Repository {
+	return &userRepository{next: r}
+}
+
+type userRepository struct {
+	next user.Repository
+}
+
+func (r *userRepository) List(ctx context.Context, q user.ListQuery) (user.Page, error) {
+	ctx, span := start(ctx, "user.Repository/List", listAttrs(q.Limit, q.Offset)...)
+	page, err := r.next.List(ctx, q)
+	end(span, err)
+	return page, err
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int) (user.User, error) {
+	ctx, span := start(ctx, "user.Repository/GetByID", attribute.Int("user.id", id))
+	u, err := r.next.GetByID(ctx, id)
+	end(span, err)
+	return u, err
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (user.User, error) {
+	ctx, span := start(ctx, "user.Repository/GetByEmail")
+	u, err := r.next.GetByEmail(ctx, email)
+	end(span, err)
+	return u, err
+}
+
+func (r *userRepository) Create(ctx context.Context, u user.User) (user.User, error) {
+	ctx, span := start(ctx, "user.Repository/Create")
+	created, err := r.next.Create(ctx, u)
+	end(span, err)
+	return created, err
+}
+
+func (r *userRepository) Update(ctx context.Context, id int, u user.User) (user.User, error) {
+	ctx, span := start(ctx, "user.Repository/Update", attribute.Int("user.id", id))
+	updated, err := r.next.Update(ctx, id, u)
+	end(span, err)
+	return updated, err
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int) error {
+	ctx, span := start(ctx, "user.Repository/Delete", attribute.Int("user.id", id))
+	err := r.next.Delete(ctx, id)
+	end(span, err)
+	return err
+}
+
+func (r *userRepository) RecordLogin(ctx context.Context, id int, at time.Time, ip string) error {
+	ctx, span := start(ctx, "user.Repository/RecordLogin", attribute.Int("user.id", id))
+	err := r.next.RecordLogin(ctx, id, at, ip)
+	end(span, err)
+	return err
+}
+
+// Ping is not traced: health checks call it often and outside any request.
+func (r *userRepository) Ping(ctx context.Context) error {
+	return r.next.Ping(ctx)
+}
+
+// listAttrs describes the page requested from a List call.
+func listAttrs(limit, offset int) []attribute.KeyValue {
+	return []attribute.KeyValue{attribute.Int("list.limit", limit), attribute.Int("list.offset", offset)}
+}
@@ -0,0 +1,11 @@
+package user
+
+import "test-backend/internal/apperr"
+
+// Errors returned by Repository and Service.
+var (
+	ErrNotFound           = apperr.New(apperr.ErrNotFound, "user not found")
+	ErrEmailTaken         = apperr.New(apperr.ErrConflict, "email already in use")
+	ErrInvalidCredentials = apperr.New(apperr.ErrUnauthorized, "invalid credentials")
+	ErrEmailChanged       = apperr.New(apperr.ErrBadRequest, "the email address has changed since the verification link was sent")
+)
@@ -2,9 +2,14 @@ package user
 
 import (
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+
+	"test-backend/internal/problem"
+	"test-backend/internal/validation"
 )
 
 // Handler handles HTTP requests for users.
@@ -19,18 +24,77 @@ func NewHandler(s Service) *Handler {
 
 // GetUsers godoc
 // @Summary      List users
-// @Description  get users
+// @Description  list users with search, sorting and pagination
 // @Tags         users
 // @Produce      json
 // @Security     BearerAuth
-// @Success      200  {array}   User
+// @Param        limit   query     int     false  "Page size (1-100)"  default(20)
+// @Param        offset  query     int     false  "Number of matching users to skip"
+// @Param        q       query     string  false  "Case-insensitive name or email prefix"
+// @Param        sort    query     string  false  "Sort field, prefix with - for descending"  Enums(id, -id, name, -name)
+// @Success      200  {object}  Page
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      422  {object}  problem.Problem
 // @Router       /users [get]
 func (h *Handler) GetUsers(c *gin.Context) {
-	users := h.service.GetAll()
-	for i := range users {
-		users[i].Password = ""
+	q, err := parseListQuery(c)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	page, err := h.service.List(c.Request.Context(), q)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	for i := range page.Items {
+		page.Items[i].Password = ""
 	}
-	c.JSON(http.StatusOK, users)
+	page.Links = pageLinks(c.Request.URL, page)
+	c.JSON(http.StatusOK, page)
+}
+
+// parseListQuery reads a ListQuery from the request's query string.
+func parseListQuery(c *gin.Context) (ListQuery, error) {
+	var v validation.Validator
+	q := ListQuery{Search: c.Query("q")}
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			v.Add("limit", "must be an integer")
+		}
+		q.Limit = n
+	}
+	if s := c.Query("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			v.Add("offset", "must be an integer")
+		}
+		q.Offset = n
+	}
+	sort := c.Query("sort")
+	q.Desc = strings.HasPrefix(sort, "-")
+	q.Sort = SortField(strings.TrimPrefix(sort, "-"))
+	return q, v.Err()
+}
+
+// pageLinks builds links to the current, first, previous and next pages of a listing at u.
+func pageLinks(u *url.URL, page Page) Links {
+	link := func(offset int) string {
+		query := u.Query()
+		query.Set("limit", strconv.Itoa(page.Limit))
+		query.Set("offset", strconv.Itoa(offset))
+		return (&url.URL{Path: u.Path, RawQuery: query.Encode()}).String()
+	}
+	links := Links{Self: link(page.Offset), First: link(0)}
+	if page.Offset > 0 {
+		links.Prev = link(max(page.Offset-page.Limit, 0))
+	}
+	if page.Offset+page.Limit < page.Total {
+		links.Next = link(page.Offset + page.Limit)
+	}
+	return links
 }
 
 // GetUser godoc
@@ -41,17 +105,20 @@ func (h *Handler) GetUsers(c *gin.Context) {
 // @Security     BearerAuth
 // @Param        id   path      int  true  "User ID"
 // @Success      200  {object}  User
-// @Failure      404  {string}  string  "not found"
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
 // @Router       /users/{id} [get]
 func (h *Handler) GetUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.Write(c, problem.New(http.StatusBadRequest, "invalid id"))
 		return
 	}
-	user, ok := h.service.GetByID(id)
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	user, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		problem.Error(c, err)
 		return
 	}
 	user.Password = ""
@@ -67,14 +134,23 @@ func (h *Handler) GetUser(c *gin.Context) {
 // @Security     BearerAuth
 // @Param        user  body      User  true  "User"
 // @Success      201   {object}  User
+// @Failure      400   {object}  problem.Problem
+// @Failure      401   {object}  problem.Problem
+// @Failure      403   {object}  problem.Problem
+// @Failure      409   {object}  problem.Problem
+// @Failure      422   {object}  problem.Problem
 // @Router       /users [post]
 func (h *Handler) CreateUser(c *gin.Context) {
 	var user User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Error(c, validation.BindError(err))
+		return
+	}
+	created, err := h.service.Create(c.Request.Context(), user)
+	if err != nil {
+		problem.Error(c, err)
 		return
 	}
-	created := h.service.Create(user)
 	created.Password = ""
 	c.JSON(http.StatusCreated, created)
 }
@@ -89,22 +165,27 @@ func (h *Handler) CreateUser(c *gin.Context) {
 // @Param        id    path      int       true  "User ID"
 // @Param        user  body      User true  "User"
 // @Success      200   {object}  User
-// @Failure      404   {string}  string    "not found"
+// @Failure      400   {object}  problem.Problem
+// @Failure      401   {object}  problem.Problem
+// @Failure      403   {object}  problem.Problem
+// @Failure      404   {object}  problem.Problem
+// @Failure      409   {object}  problem.Problem
+// @Failure      422   {object}  problem.Problem
 // @Router       /users/{id} [put]
 func (h *Handler) UpdateUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.Write(c, problem.New(http.StatusBadRequest, "invalid id"))
 		return
 	}
 	var user User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Error(c, validation.BindError(err))
 		return
 	}
-	updated, ok := h.service.Update(id, user)
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	updated, err := h.service.Update(c.Request.Context(), id, user)
+	if err != nil {
+		problem.Error(c, err)
 		return
 	}
 	updated.Password = ""
@@ -119,16 +200,60 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 // @Security     BearerAuth
 // @Param        id   path      int  true  "User ID"
 // @Success      204  {string}  string  ""
-// @Failure      404  {string}  string  "not found"
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
 // @Router       /users/{id} [delete]
 func (h *Handler) DeleteUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.Write(c, problem.New(http.StatusBadRequest, "invalid id"))
+		return
+	}
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetLockouts godoc
+// @Summary      List login lockouts
+// @Description  list accounts and client IPs with recent failed logins, most recent first
+// @Tags         users
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   Lockout
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Router       /lockouts [get]
+func (h *Handler) GetLockouts(c *gin.Context) {
+	lockouts, err := h.service.Lockouts(c.Request.Context())
+	if err != nil {
+		problem.Error(c, err)
 		return
 	}
-	if !h.service.Delete(id) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	c.JSON(http.StatusOK, lockouts)
+}
+
+// ClearLockout godoc
+// @Summary      Clear login lockout
+// @Description  forget the failed logins of an account (by email) or client IP, lifting any lock
+// @Tags         users
+// @Produce      json
+// @Security     BearerAuth
+// @Param        kind  path      string  true  "Lockout kind"  Enums(account, ip)
+// @Param        key   path      string  true  "Email address or client IP"
+// @Success      204   {string}  string  ""
+// @Failure      401   {object}  problem.Problem
+// @Failure      403   {object}  problem.Problem
+// @Failure      404   {object}  problem.Problem
+// @Failure      422   {object}  problem.Problem
+// @Router       /lockouts/{kind}/{key} [delete]
+func (h *Handler) ClearLockout(c *gin.Context) {
+	if err := h.service.ClearLockout(c.Request.Context(), LockoutKind(c.Param("kind")), c.Param("key")); err != nil {
+		problem.Error(c, err)
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -0,0 +1,178 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"test-backend/internal/apperr"
+)
+
+// LockoutKind is what a Lockout counts failed logins against.
+type LockoutKind string
+
+// Lockout kinds.
+const (
+	// LockoutAccount counts failures per email address, whether or not an
+	// account exists for it, so lockouts do not reveal which emails are registered.
+	LockoutAccount LockoutKind = "account"
+	// LockoutIP counts failures per client IP across all accounts.
+	LockoutIP LockoutKind = "ip"
+)
+
+// Valid reports whether k is a known lockout kind.
+func (k LockoutKind) Valid() bool {
+	return k == LockoutAccount || k == LockoutIP
+}
+
+// Lockout records the recent failed logins of an account or client IP.
+type Lockout struct {
+	Kind          LockoutKind `json:"kind" enums:"account,ip"`
+	Key           string      `json:"key"`
+	Failures      int         `json:"failures"`
+	LastFailureAt time.Time   `json:"last_failure_at"`
+	// LockedUntil is set once Failures reaches the policy's threshold.
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
+
+// Locked reports whether logins are rejected at now.
+func (l Lockout) Locked(now time.Time) bool {
+	return l.LockedUntil != nil && now.Before(*l.LockedUntil)
+}
+
+// LockoutPolicy decides when failed logins lock an account or client IP.
+type LockoutPolicy struct {
+	// Threshold is the number of failures after which an account is locked.
+	Threshold int
+	// IPThreshold is the number of failures after which a client IP is locked.
+	IPThreshold int
+	// BaseDuration is how long the first lockout lasts. Every further failure
+	// doubles it, up to MaxDuration.
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+	// ResetAfter is how long failures are remembered after the last one.
+	ResetAfter time.Duration
+}
+
+// fail returns l with another failure recorded at now, locked if the policy says so.
+func (p LockoutPolicy) fail(l Lockout, now time.Time) Lockout {
+	if now.Sub(l.LastFailureAt) > p.ResetAfter {
+		l.Failures = 0
+	}
+	l.Failures++
+	l.LastFailureAt = now
+
+	threshold := p.Threshold
+	if l.Kind == LockoutIP {
+		threshold = p.IPThreshold
+	}
+	if l.Failures < threshold {
+		return l
+	}
+	d := p.MaxDuration
+	if backoff := float64(p.BaseDuration) * math.Exp2(float64(l.Failures-threshold)); backoff < float64(d) {
+		d = time.Duration(backoff)
+	}
+	until := now.Add(d)
+	l.LockedUntil = &until
+	return l
+}
+
+// stale reports whether l no longer affects logins at now and can be forgotten.
+func (p LockoutPolicy) stale(l Lockout, now time.Time) bool {
+	return !l.Locked(now) && now.Sub(l.LastFailureAt) > p.ResetAfter
+}
+
+// LockedError is returned by Authenticate while the account or client IP is locked.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed logins, try again in %d seconds", int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+func (e *LockedError) Unwrap() error { return apperr.ErrTooManyRequests }
+
+// ErrLockoutNotFound is returned when no failures are recorded for an account or client IP.
+var ErrLockoutNotFound = apperr.New(apperr.ErrNotFound, "lockout not found")
+
+// LockoutStore keeps track of failed logins.
+type LockoutStore interface {
+	// Get returns the failures recorded for key, or a Lockout without failures if there are none.
+	Get(ctx context.Context, kind LockoutKind, key string) (Lockout, error)
+	// Fail records a failed login at now and returns the updated Lockout.
+	// Entries made stale by policy are pruned.
+	Fail(ctx context.Context, kind LockoutKind, key string, policy LockoutPolicy, now time.Time) (Lockout, error)
+	// Delete forgets the failures recorded for key.
+	Delete(ctx context.Context, kind LockoutKind, key string) error
+	List(ctx context.Context) ([]Lockout, error)
+}
+
+// lockoutKey identifies an entry of InMemoryLockoutStore.
+type lockoutKey struct {
+	kind LockoutKind
+	key  string
+}
+
+// InMemoryLockoutStore is an in-memory implementation of LockoutStore.
+// It is safe for concurrent use.
+type InMemoryLockoutStore struct {
+	mu      sync.Mutex
+	entries map[lockoutKey]Lockout
+}
+
+// NewInMemoryLockoutStore creates a new in-memory lockout store.
+func NewInMemoryLockoutStore() *InMemoryLockoutStore {
+	return &InMemoryLockoutStore{entries: make(map[lockoutKey]Lockout)}
+}
+
+func (s *InMemoryLockoutStore) Get(ctx context.Context, kind LockoutKind, key string) (Lockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.entries[lockoutKey{kind, key}]; ok {
+		return l, nil
+	}
+	return Lockout{Kind: kind, Key: key}, nil
+}
+
+func (s *InMemoryLockoutStore) Fail(ctx context.Context, kind LockoutKind, key string, policy LockoutPolicy, now time.Time) (Lockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, l := range s.entries {
+		if policy.stale(l, now) {
+			delete(s.entries, k)
+		}
+	}
+	k := lockoutKey{kind, key}
+	l, ok := s.entries[k]
+	if !ok {
+		l = Lockout{Kind: kind, Key: key}
+	}
+	l = policy.fail(l, now)
+	s.entries[k] = l
+	return l, nil
+}
+
+func (s *InMemoryLockoutStore) Delete(ctx context.Context, kind LockoutKind, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := lockoutKey{kind, key}
+	if _, ok := s.entries[k]; !ok {
+		return ErrLockoutNotFound
+	}
+	delete(s.entries, k)
+	return nil
+}
+
+func (s *InMemoryLockoutStore) List(ctx context.Context) ([]Lockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lockouts := make([]Lockout, 0, len(s.entries))
+	for _, l := range s.entries {
+		lockouts = append(lockouts, l)
+	}
+	return lockouts, nil
+}
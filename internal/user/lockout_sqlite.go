@@ -0,0 +1,119 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SQLiteLockoutStore is a SQLite implementation of LockoutStore. Instances
+// sharing the database file share their lockouts.
+type SQLiteLockoutStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteLockoutStore creates a new SQLite lockout store.
+// The schema is managed by the migrations package.
+func NewSQLiteLockoutStore(db *sql.DB) *SQLiteLockoutStore {
+	return &SQLiteLockoutStore{db: db}
+}
+
+// scanLockout reads a row of login_lockouts selected with all its columns.
+func scanLockout(row interface{ Scan(...any) error }) (Lockout, error) {
+	var l Lockout
+	var lockedUntil sql.NullTime
+	err := row.Scan(&l.Kind, &l.Key, &l.Failures, &l.LastFailureAt, &lockedUntil)
+	if lockedUntil.Valid {
+		l.LockedUntil = &lockedUntil.Time
+	}
+	return l, err
+}
+
+// lockedUntilValue stores an unset lock as NULL.
+func lockedUntilValue(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return nullTime(*t)
+}
+
+func (s *SQLiteLockoutStore) Get(ctx context.Context, kind LockoutKind, key string) (Lockout, error) {
+	l, err := scanLockout(s.db.QueryRowContext(ctx,
+		`SELECT kind, key, failures, last_failure_at, locked_until FROM login_lockouts WHERE kind = ? AND key = ?`, kind, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Lockout{Kind: kind, Key: key}, nil
+	}
+	return l, err
+}
+
+func (s *SQLiteLockoutStore) Fail(ctx context.Context, kind LockoutKind, key string, policy LockoutPolicy, now time.Time) (Lockout, error) {
+	now = now.UTC()
+	// Entries are pruned with the same rule as policy.stale.
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM login_lockouts WHERE last_failure_at < ? AND (locked_until IS NULL OR locked_until <= ?)`,
+		now.Add(-policy.ResetAfter), now)
+	if err != nil {
+		return Lockout{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Lockout{}, err
+	}
+	defer tx.Rollback()
+	// Write first so the transaction takes the write lock before reading;
+	// upgrading a read transaction can fail when another process is writing.
+	if _, err := tx.ExecContext(ctx, `UPDATE login_lockouts SET failures = failures WHERE kind = ? AND key = ?`, kind, key); err != nil {
+		return Lockout{}, err
+	}
+	l, err := scanLockout(tx.QueryRowContext(ctx,
+		`SELECT kind, key, failures, last_failure_at, locked_until FROM login_lockouts WHERE kind = ? AND key = ?`, kind, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		l, err = Lockout{Kind: kind, Key: key}, nil
+	}
+	if err != nil {
+		return Lockout{}, err
+	}
+	l = policy.fail(l, now)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO login_lockouts (kind, key, failures, last_failure_at, locked_until) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (kind, key) DO UPDATE SET failures = excluded.failures,
+			last_failure_at = excluded.last_failure_at, locked_until = excluded.locked_until`,
+		l.Kind, l.Key, l.Failures, l.LastFailureAt, lockedUntilValue(l.LockedUntil))
+	if err != nil {
+		return Lockout{}, err
+	}
+	return l, tx.Commit()
+}
+
+func (s *SQLiteLockoutStore) Delete(ctx context.Context, kind LockoutKind, key string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM login_lockouts WHERE kind = ? AND key = ?`, kind, key)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrLockoutNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteLockoutStore) List(ctx context.Context) ([]Lockout, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT kind, key, failures, last_failure_at, locked_until FROM login_lockouts`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	lockouts := []Lockout{}
+	for rows.Next() {
+		l, err := scanLockout(rows)
+		if err != nil {
+			return nil, err
+		}
+		lockouts = append(lockouts, l)
+	}
+	return lockouts, rows.Err()
+}
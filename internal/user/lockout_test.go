@@ -0,0 +1,235 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"test-backend/internal/apperr"
+	"test-backend/internal/database/dbtest"
+)
+
+func TestLockoutPolicyFail(t *testing.T) {
+	policy := LockoutPolicy{
+		Threshold:    3,
+		IPThreshold:  5,
+		BaseDuration: time.Minute,
+		MaxDuration:  5 * time.Minute,
+		ResetAfter:   time.Hour,
+	}
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		at       time.Duration
+		failures int
+		// lock is how long the lock lasts from the failure, or 0 if unlocked.
+		lock time.Duration
+	}{
+		{"below threshold", 0, 1, 0},
+		{"still below", time.Second, 2, 0},
+		{"threshold locks for the base duration", 2 * time.Second, 3, time.Minute},
+		{"next failure doubles", 2 * time.Minute, 4, 2 * time.Minute},
+		{"and doubles again", 5 * time.Minute, 5, 4 * time.Minute},
+		{"capped at the maximum", 10 * time.Minute, 6, 5 * time.Minute},
+		{"forgotten after ResetAfter", 10*time.Minute + time.Hour + time.Second, 1, 0},
+	}
+	l := Lockout{Kind: LockoutAccount, Key: "alice@example.com"}
+	for _, tt := range tests {
+		now := t0.Add(tt.at)
+		l = policy.fail(l, now)
+		if l.Failures != tt.failures {
+			t.Errorf("%s: Failures = %d, want %d", tt.name, l.Failures, tt.failures)
+		}
+		if tt.lock == 0 {
+			if l.Locked(now) {
+				t.Errorf("%s: locked until %s, want unlocked", tt.name, l.LockedUntil)
+			}
+			continue
+		}
+		if l.LockedUntil == nil || !l.LockedUntil.Equal(now.Add(tt.lock)) {
+			t.Errorf("%s: LockedUntil = %v, want %s", tt.name, l.LockedUntil, now.Add(tt.lock))
+		}
+		if !l.Locked(now.Add(tt.lock-time.Nanosecond)) || l.Locked(now.Add(tt.lock)) {
+			t.Errorf("%s: lock does not end after %s", tt.name, tt.lock)
+		}
+	}
+}
+
+func TestLockoutPolicyFailIPThreshold(t *testing.T) {
+	policy := LockoutPolicy{Threshold: 2, IPThreshold: 4, BaseDuration: time.Minute, MaxDuration: time.Hour, ResetAfter: time.Hour}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := Lockout{Kind: LockoutIP, Key: "192.0.2.1"}
+	for i := 1; i < 4; i++ {
+		if l = policy.fail(l, now); l.Locked(now) {
+			t.Fatalf("IP locked after %d failures, want %d", i, 4)
+		}
+	}
+	if l = policy.fail(l, now); !l.Locked(now) {
+		t.Fatalf("IP not locked after %d failures", l.Failures)
+	}
+}
+
+func TestLockoutPolicyStale(t *testing.T) {
+	policy := LockoutPolicy{Threshold: 1, IPThreshold: 1, BaseDuration: 2 * time.Hour, MaxDuration: 2 * time.Hour, ResetAfter: time.Hour}
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := policy.fail(Lockout{Kind: LockoutAccount, Key: "alice@example.com"}, t0)
+
+	tests := []struct {
+		name  string
+		at    time.Duration
+		stale bool
+	}{
+		{"recent", time.Minute, false},
+		{"past ResetAfter but still locked", time.Hour + time.Minute, false},
+		{"lock expired", 2*time.Hour + time.Second, true},
+	}
+	for _, tt := range tests {
+		if got := policy.stale(l, t0.Add(tt.at)); got != tt.stale {
+			t.Errorf("%s: stale = %v, want %v", tt.name, got, tt.stale)
+		}
+	}
+}
+
+// testLockoutStores returns an empty lockout store of each backend.
+func testLockoutStores(t *testing.T) map[string]LockoutStore {
+	return map[string]LockoutStore{
+		"memory": NewInMemoryLockoutStore(),
+		"sqlite": NewSQLiteLockoutStore(dbtest.Open(t)),
+	}
+}
+
+func TestLockoutStoreFail(t *testing.T) {
+	ctx := context.Background()
+	policy := LockoutPolicy{Threshold: 2, IPThreshold: 3, BaseDuration: time.Minute, MaxDuration: 3 * time.Minute, ResetAfter: time.Hour}
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	const email = "alice@example.com"
+
+	tests := []struct {
+		name     string
+		at       time.Duration
+		failures int
+		// lockedFor is how long the account is locked from the failure, or 0.
+		lockedFor time.Duration
+	}{
+		{"first failure", 0, 1, 0},
+		{"threshold", time.Second, 2, time.Minute},
+		{"escalates", 2 * time.Minute, 3, 2 * time.Minute},
+		{"capped", 5 * time.Minute, 4, 3 * time.Minute},
+		{"forgotten after ResetAfter", 8*time.Minute + time.Hour + time.Second, 1, 0},
+	}
+	for backend, store := range testLockoutStores(t) {
+		for _, tt := range tests {
+			now := t0.Add(tt.at)
+			l, err := store.Fail(ctx, LockoutAccount, email, policy, now)
+			if err != nil {
+				t.Fatalf("%s: %s: Fail: %v", backend, tt.name, err)
+			}
+			got, err := store.Get(ctx, LockoutAccount, email)
+			if err != nil {
+				t.Fatalf("%s: %s: Get: %v", backend, tt.name, err)
+			}
+			for _, l := range []Lockout{l, got} {
+				if l.Failures != tt.failures || !l.LastFailureAt.Equal(now) {
+					t.Errorf("%s: %s: %d failures, last at %s, want %d at %s",
+						backend, tt.name, l.Failures, l.LastFailureAt, tt.failures, now)
+				}
+				if tt.lockedFor == 0 {
+					if l.Locked(now) {
+						t.Errorf("%s: %s: locked until %s, want unlocked", backend, tt.name, l.LockedUntil)
+					}
+					continue
+				}
+				if !l.Locked(now.Add(tt.lockedFor-time.Second)) || l.Locked(now.Add(tt.lockedFor)) {
+					t.Errorf("%s: %s: locked until %v, want %s", backend, tt.name, l.LockedUntil, now.Add(tt.lockedFor))
+				}
+			}
+		}
+	}
+}
+
+func TestLockoutStorePrunesExpiredLocks(t *testing.T) {
+	ctx := context.Background()
+	policy := LockoutPolicy{Threshold: 1, IPThreshold: 1, BaseDuration: 2 * time.Hour, MaxDuration: 2 * time.Hour, ResetAfter: time.Hour}
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for backend, store := range testLockoutStores(t) {
+		if _, err := store.Fail(ctx, LockoutAccount, "locked@example.com", policy, t0); err != nil {
+			t.Fatalf("%s: Fail: %v", backend, err)
+		}
+		// Past ResetAfter, but the lock still holds: the entry stays.
+		if _, err := store.Fail(ctx, LockoutIP, "192.0.2.1", policy, t0.Add(90*time.Minute)); err != nil {
+			t.Fatalf("%s: Fail: %v", backend, err)
+		}
+		if l, _ := store.Get(ctx, LockoutAccount, "locked@example.com"); !l.Locked(t0.Add(90 * time.Minute)) {
+			t.Errorf("%s: account unlocked before its lock expired", backend)
+		}
+		// Once the lock has expired the entry is pruned by the next failure.
+		if _, err := store.Fail(ctx, LockoutIP, "192.0.2.2", policy, t0.Add(2*time.Hour+time.Second)); err != nil {
+			t.Fatalf("%s: Fail: %v", backend, err)
+		}
+		l, err := store.Get(ctx, LockoutAccount, "locked@example.com")
+		if err != nil {
+			t.Fatalf("%s: Get: %v", backend, err)
+		}
+		if l.Failures != 0 || l.LockedUntil != nil {
+			t.Errorf("%s: expired lockout not pruned: %+v", backend, l)
+		}
+		all, err := store.List(ctx)
+		if err != nil {
+			t.Fatalf("%s: List: %v", backend, err)
+		}
+		if len(all) != 2 {
+			t.Errorf("%s: List = %+v, want the two IP entries", backend, all)
+		}
+	}
+}
+
+func TestServiceClearLockout(t *testing.T) {
+	ctx := context.Background()
+	policy := LockoutPolicy{Threshold: 1, IPThreshold: 1, BaseDuration: time.Hour, MaxDuration: time.Hour, ResetAfter: time.Hour}
+	now := time.Now()
+
+	for backend, store := range testLockoutStores(t) {
+		s := NewService(NewInMemoryRepository(), bcrypt.MinCost, store, policy, NewInMemoryTwoFactorStore())
+		if _, err := store.Fail(ctx, LockoutAccount, "alice@example.com", policy, now); err != nil {
+			t.Fatalf("%s: Fail: %v", backend, err)
+		}
+		if _, err := store.Fail(ctx, LockoutIP, "192.0.2.1", policy, now); err != nil {
+			t.Fatalf("%s: Fail: %v", backend, err)
+		}
+		var locked *LockedError
+		if _, err := s.Authenticate(ctx, "alice@example.com", "whatever1", "198.51.100.1"); !errors.As(err, &locked) {
+			t.Fatalf("%s: Authenticate while locked: err = %v, want *LockedError", backend, err)
+		}
+
+		// Account keys are matched regardless of case.
+		if err := s.ClearLockout(ctx, LockoutAccount, "ALICE@example.com"); err != nil {
+			t.Fatalf("%s: ClearLockout: %v", backend, err)
+		}
+		if _, err := s.Authenticate(ctx, "alice@example.com", "whatever1", "198.51.100.1"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: Authenticate after clearing: err = %v, want ErrInvalidCredentials", backend, err)
+		}
+		if err := s.ClearLockout(ctx, LockoutAccount, "nobody@example.com"); !errors.Is(err, ErrLockoutNotFound) {
+			t.Errorf("%s: ClearLockout of unknown account: err = %v, want ErrLockoutNotFound", backend, err)
+		}
+		lockouts, err := s.Lockouts(ctx)
+		if err != nil {
+			t.Fatalf("%s: Lockouts: %v", backend, err)
+		}
+		for _, l := range lockouts {
+			if l.Kind == LockoutAccount && l.Key == "alice@example.com" && l.Failures != 1 {
+				t.Errorf("%s: Lockouts = %+v, want the cleared account to start over", backend, lockouts)
+			}
+		}
+		if err := s.ClearLockout(ctx, LockoutIP, "192.0.2.1"); err != nil {
+			t.Errorf("%s: ClearLockout of IP: %v", backend, err)
+		}
+		if err := s.ClearLockout(ctx, "user", "x"); !errors.Is(err, apperr.ErrInvalid) {
+			t.Errorf("%s: ClearLockout of unknown kind: err = %v, want a validation error", backend, err)
+		}
+	}
+}
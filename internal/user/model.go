@@ -1,9 +1,69 @@
 package user
 
+import (
+	"log/slog"
+	"time"
+
+	"test-backend/internal/validation"
+)
+
+// MaxNameLength is the longest name a user may have.
+const MaxNameLength = 255
+
+// Role determines what a user is allowed to do.
+type Role string
+
+// Roles, from most to least privileged.
+const (
+	// RoleAdmin can manage users and products.
+	RoleAdmin Role = "admin"
+	// RoleEditor can manage products.
+	RoleEditor Role = "editor"
+	// RoleViewer can only read products.
+	RoleViewer Role = "viewer"
+)
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	return r == RoleAdmin || r == RoleEditor || r == RoleViewer
+}
+
 // User represents a user in the system.
 type User struct {
 	ID       int    `json:"id"`
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Password string `json:"password,omitempty"`
+	Role     Role   `json:"role,omitempty" enums:"admin,editor,viewer"`
+	// EmailVerified is set once the user follows a verification link sent to
+	// Email. Admins may set it when creating a user; it is cleared whenever
+	// the email changes.
+	EmailVerified bool `json:"email_verified"`
+	// PasswordChangedAt is when the password was last changed. Tokens issued
+	// before it are rejected.
+	PasswordChangedAt time.Time `json:"-"`
+	// LastLoginAt and LastLoginIP describe the last successful login. They are
+	// set by Authenticate and ignored on create and update.
+	LastLoginAt *time.Time `json:"last_login_at,omitempty" readonly:"true"`
+	LastLoginIP string     `json:"last_login_ip,omitempty" readonly:"true"`
+}
+
+// LogValue implements slog.LogValuer so the password hash is never logged.
+func (u User) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("id", u.ID), slog.String("email", u.Email), slog.String("role", string(u.Role)))
+}
+
+// Validate checks the user's fields. The password and role are only checked
+// when set, since updates may leave them unchanged.
+func (u User) Validate() error {
+	var v validation.Validator
+	v.MaxLength("name", u.Name, MaxNameLength)
+	v.Email("email", u.Email)
+	if u.Password != "" {
+		v.Password("password", u.Password)
+	}
+	if u.Role != "" && !u.Role.Valid() {
+		v.Add("role", "must be one of admin, editor or viewer")
+	}
+	return v.Err()
 }
@@ -0,0 +1,60 @@
+package user
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"test-backend/internal/apperr"
+	"test-backend/internal/validation"
+)
+
+func TestUserValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		user User
+		want validation.Errors
+	}{
+		{"valid", User{Name: "Alice", Email: "alice@example.com", Password: "password1", Role: RoleEditor}, nil},
+		{"password and role may be left unset", User{Email: "alice@example.com"}, nil},
+		{"name too long", User{Name: strings.Repeat("é", MaxNameLength+1), Email: "alice@example.com"},
+			validation.Errors{{Field: "name", Reason: "must be at most 255 characters"}}},
+		{"missing email", User{Name: "Alice"},
+			validation.Errors{{Field: "email", Reason: "is required"}}},
+		{"email with display name", User{Email: "Alice <alice@example.com>"},
+			validation.Errors{{Field: "email", Reason: "must be a valid email address"}}},
+		{"short password", User{Email: "alice@example.com", Password: "pass1"},
+			validation.Errors{{Field: "password", Reason: "must be at least 8 characters"}}},
+		{"password too long", User{Email: "alice@example.com", Password: strings.Repeat("a1", 37)},
+			validation.Errors{{Field: "password", Reason: "must be at most 72 bytes"}}},
+		{"password without digit", User{Email: "alice@example.com", Password: "password"},
+			validation.Errors{{Field: "password", Reason: "must contain at least one letter and one digit"}}},
+		{"unknown role", User{Email: "alice@example.com", Role: "owner"},
+			validation.Errors{{Field: "role", Reason: "must be one of admin, editor or viewer"}}},
+		{"every field reported", User{Name: strings.Repeat("a", MaxNameLength+1), Email: "nope", Password: "12345678", Role: "owner"},
+			validation.Errors{
+				{Field: "name", Reason: "must be at most 255 characters"},
+				{Field: "email", Reason: "must be a valid email address"},
+				{Field: "password", Reason: "must contain at least one letter and one digit"},
+				{Field: "role", Reason: "must be one of admin, editor or viewer"},
+			}},
+	}
+	for _, tt := range tests {
+		err := tt.user.Validate()
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("%s: Validate = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+		var got validation.Errors
+		if !errors.As(err, &got) || !errors.Is(err, apperr.ErrInvalid) {
+			t.Errorf("%s: Validate = %v, want validation errors", tt.name, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Validate = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package user
+
+import (
+	"strings"
+
+	"test-backend/internal/validation"
+)
+
+// Paging limits for List.
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// SortField is a user field that List can order by.
+type SortField string
+
+// Sortable fields.
+const (
+	SortByID   SortField = "id"
+	SortByName SortField = "name"
+)
+
+// ListQuery searches, orders and pages a user listing.
+type ListQuery struct {
+	Limit  int
+	Offset int
+	// Search matches users whose name or email starts with it, ignoring case.
+	Search string
+	Sort   SortField
+	Desc   bool
+}
+
+// Page is one page of a user listing.
+type Page struct {
+	Items []User `json:"items"`
+	// Total is the number of users matching the search across all pages.
+	Total  int   `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+	Links  Links `json:"links"`
+}
+
+// Links point to neighbouring pages of a listing. Prev and Next are empty at either end.
+type Links struct {
+	Self  string `json:"self"`
+	First string `json:"first"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+}
+
+// Normalize fills in defaults and validates q.
+func (q *ListQuery) Normalize() error {
+	var v validation.Validator
+	switch {
+	case q.Limit == 0:
+		q.Limit = DefaultLimit
+	case q.Limit < 1 || q.Limit > MaxLimit:
+		v.Range("limit", float64(q.Limit), 1, MaxLimit)
+	}
+	if q.Offset < 0 {
+		v.Add("offset", "must not be negative")
+	}
+	if q.Sort == "" {
+		q.Sort = SortByID
+	}
+	if q.Sort != SortByID && q.Sort != SortByName {
+		v.Add("sort", "must be one of id or name")
+	}
+	q.Search = strings.TrimSpace(q.Search)
+	return v.Err()
+}
+
+// matches reports whether u passes the query's search.
+func (q ListQuery) matches(u User) bool {
+	if q.Search == "" {
+		return true
+	}
+	search := strings.ToLower(q.Search)
+	return strings.HasPrefix(strings.ToLower(u.Name), search) ||
+		strings.HasPrefix(strings.ToLower(u.Email), search)
+}
+
+// compare orders a before b by the query's sort field, breaking ties by ID.
+func (q ListQuery) compare(a, b User) int {
+	c := 0
+	if q.Sort == SortByName {
+		c = strings.Compare(a.Name, b.Name)
+	}
+	if c == 0 {
+		c = a.ID - b.ID
+	}
+	if q.Desc {
+		return -c
+	}
+	return c
+}
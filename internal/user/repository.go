@@ -1,19 +1,35 @@
 package user
 
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 // Repository defines methods for user data access.
 type Repository interface {
-	GetAll() []User
-	GetByID(id int) (User, bool)
-	GetByEmail(email string) (User, bool)
-	Create(user User) User
-	Update(id int, user User) (User, bool)
-	Delete(id int) bool
+	// List returns the page of users selected by a normalized query.
+	List(ctx context.Context, q ListQuery) (Page, error)
+	GetByID(ctx context.Context, id int) (User, error)
+	GetByEmail(ctx context.Context, email string) (User, error)
+	Create(ctx context.Context, user User) (User, error)
+	Update(ctx context.Context, id int, user User) (User, error)
+	Delete(ctx context.Context, id int) error
+	// RecordLogin stores the time and client IP of a successful login.
+	RecordLogin(ctx context.Context, id int, at time.Time, ip string) error
+	// Ping reports whether the repository can currently serve requests.
+	Ping(ctx context.Context) error
 }
 
 // InMemoryRepository is an in-memory implementation of Repository.
+// It is safe for concurrent use.
 type InMemoryRepository struct {
+	mu     sync.RWMutex
 	data   map[int]User
-	lastID int
+	lastID atomic.Int64
 }
 
 // NewInMemoryRepository creates a new in-memory repository.
@@ -21,48 +37,102 @@ func NewInMemoryRepository() *InMemoryRepository {
 	return &InMemoryRepository{data: make(map[int]User)}
 }
 
-func (r *InMemoryRepository) GetAll() []User {
-	users := make([]User, 0, len(r.data))
+func (r *InMemoryRepository) List(ctx context.Context, q ListQuery) (Page, error) {
+	r.mu.RLock()
+	matched := make([]User, 0, len(r.data))
 	for _, u := range r.data {
-		users = append(users, u)
+		if q.matches(u) {
+			matched = append(matched, u)
+		}
 	}
-	return users
+	r.mu.RUnlock()
+	sort.Slice(matched, func(i, j int) bool { return q.compare(matched[i], matched[j]) < 0 })
+
+	start := min(q.Offset, len(matched))
+	end := min(start+q.Limit, len(matched))
+	return Page{Items: matched[start:end], Total: len(matched), Limit: q.Limit, Offset: q.Offset}, nil
 }
 
-func (r *InMemoryRepository) GetByID(id int) (User, bool) {
+func (r *InMemoryRepository) GetByID(ctx context.Context, id int) (User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	u, ok := r.data[id]
-	return u, ok
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return u, nil
 }
 
-func (r *InMemoryRepository) GetByEmail(email string) (User, bool) {
+func (r *InMemoryRepository) GetByEmail(ctx context.Context, email string) (User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	for _, u := range r.data {
-		if u.Email == email {
-			return u, true
+		if strings.EqualFold(u.Email, email) {
+			return u, nil
 		}
 	}
-	return User{}, false
+	return User{}, ErrNotFound
 }
 
-func (r *InMemoryRepository) Create(user User) User {
-	r.lastID++
-	user.ID = r.lastID
+func (r *InMemoryRepository) Create(ctx context.Context, user User) (User, error) {
+	user.ID = int(r.lastID.Add(1))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.emailTaken(user.Email, user.ID) {
+		return User{}, ErrEmailTaken
+	}
 	r.data[user.ID] = user
-	return user
+	return user, nil
 }
 
-func (r *InMemoryRepository) Update(id int, user User) (User, bool) {
+func (r *InMemoryRepository) Update(ctx context.Context, id int, user User) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if _, ok := r.data[id]; !ok {
-		return User{}, false
+		return User{}, ErrNotFound
+	}
+	if r.emailTaken(user.Email, id) {
+		return User{}, ErrEmailTaken
 	}
 	user.ID = id
 	r.data[id] = user
-	return user, true
+	return user, nil
 }
 
-func (r *InMemoryRepository) Delete(id int) bool {
+func (r *InMemoryRepository) Delete(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if _, ok := r.data[id]; !ok {
-		return false
+		return ErrNotFound
 	}
 	delete(r.data, id)
-	return true
+	return nil
+}
+
+func (r *InMemoryRepository) RecordLogin(ctx context.Context, id int, at time.Time, ip string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.data[id]
+	if !ok {
+		return ErrNotFound
+	}
+	u.LastLoginAt = &at
+	u.LastLoginIP = ip
+	r.data[id] = u
+	return nil
+}
+
+func (r *InMemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+// emailTaken reports whether a user other than id already has email.
+// The caller must hold r.mu.
+func (r *InMemoryRepository) emailTaken(email string, id int) bool {
+	for _, u := range r.data {
+		if u.ID != id && strings.EqualFold(u.Email, email) {
+			return true
+		}
+	}
+	return false
 }
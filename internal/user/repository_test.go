@@ -0,0 +1,69 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInMemoryRepositoryConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepository()
+	const workers = 50
+
+	ids := make(chan int, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			email := fmt.Sprintf("user%d@example.com", i)
+			created, err := repo.Create(ctx, User{Name: "user", Email: email})
+			if err != nil {
+				t.Errorf("Create: %v", err)
+				return
+			}
+			ids <- created.ID
+
+			if _, err := repo.GetByID(ctx, created.ID); err != nil {
+				t.Errorf("GetByID(%d): %v", created.ID, err)
+			}
+			if _, err := repo.GetByEmail(ctx, email); err != nil {
+				t.Errorf("GetByEmail(%q): %v", email, err)
+			}
+			if _, err := repo.List(ctx, ListQuery{Limit: MaxLimit, Sort: SortByName, Search: "user"}); err != nil {
+				t.Errorf("List: %v", err)
+			}
+			if _, err := repo.Update(ctx, created.ID, User{Name: "updated", Email: email}); err != nil {
+				t.Errorf("Update(%d): %v", created.ID, err)
+			}
+			if err := repo.RecordLogin(ctx, created.ID, time.Now(), "127.0.0.1"); err != nil {
+				t.Errorf("RecordLogin(%d): %v", created.ID, err)
+			}
+			if i%2 == 0 {
+				if err := repo.Delete(ctx, created.ID); err != nil {
+					t.Errorf("Delete(%d): %v", created.ID, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[int]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate id %d", id)
+		}
+		seen[id] = true
+	}
+	page, err := repo.List(ctx, ListQuery{Limit: MaxLimit, Sort: SortByID})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if page.Total != workers/2 {
+		t.Fatalf("List() found %d users, want %d", page.Total, workers/2)
+	}
+}
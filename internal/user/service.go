@@ -1,67 +1,530 @@
 package user
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"test-backend/internal/logging"
+	"test-backend/internal/totp"
+	"test-backend/internal/validation"
+)
 
 // Service defines business logic for users.
 type Service interface {
-	GetAll() []User
-	GetByID(id int) (User, bool)
-	GetByEmail(email string) (User, bool)
-	Create(user User) User
-	Update(id int, user User) (User, bool)
-	Delete(id int) bool
-	Authenticate(email, password string) (User, bool)
+	List(ctx context.Context, q ListQuery) (Page, error)
+	GetByID(ctx context.Context, id int) (User, error)
+	GetByEmail(ctx context.Context, email string) (User, error)
+	Create(ctx context.Context, user User) (User, error)
+	Update(ctx context.Context, id int, user User) (User, error)
+	// UpdateProfile is Update for users changing their own account. Changing
+	// the email or password requires currentPassword, and wrong passwords
+	// count towards the account's lockout.
+	UpdateProfile(ctx context.Context, id int, user User, currentPassword string) (User, error)
+	Delete(ctx context.Context, id int) error
+	// Authenticate checks a login attempt from the client at ip. Failures are
+	// counted against the email and ip, which are locked out with a
+	// *LockedError once the lockout policy's thresholds are reached. Successful
+	// logins are recorded on the user. For users with two-factor
+	// authentication enabled, the login is only complete, and recorded, once
+	// AuthenticateSecondFactor succeeds.
+	Authenticate(ctx context.Context, email, password, ip string) (User, error)
+	// AuthenticateSecondFactor completes the login of user id from ip with a
+	// TOTP code or recovery code. Failures count towards the same lockouts as
+	// Authenticate.
+	AuthenticateSecondFactor(ctx context.Context, id int, code, ip string) (User, error)
+	// TwoFactorEnabled reports whether logins of user id need a second factor.
+	TwoFactorEnabled(ctx context.Context, id int) (bool, error)
+	// EnrollTOTP starts setting up TOTP for user id and returns the new secret,
+	// which replaces any unconfirmed one. It returns ErrTwoFactorEnabled if
+	// two-factor authentication is already on.
+	EnrollTOTP(ctx context.Context, id int) ([]byte, error)
+	// ConfirmTOTP enables two-factor authentication for user id once code
+	// matches the enrolled secret, and returns RecoveryCodeCount single-use
+	// recovery codes. Only their hashes are stored.
+	ConfirmTOTP(ctx context.Context, id int, code string) ([]string, error)
+	// DisableTOTP turns two-factor authentication off for user id after
+	// checking a TOTP code or recovery code.
+	DisableTOTP(ctx context.Context, id int, code string) error
+	// Lockouts lists the accounts and client IPs with recent failed logins,
+	// most recent first.
+	Lockouts(ctx context.Context) ([]Lockout, error)
+	// ClearLockout forgets the failed logins of an account or client IP, lifting any lock.
+	ClearLockout(ctx context.Context, kind LockoutKind, key string) error
+	// VerifyEmail marks the email of user id as verified. It returns
+	// ErrEmailChanged if the user's email is no longer email.
+	VerifyEmail(ctx context.Context, id int, email string) (User, error)
+	// EnsureAdmin makes sure an admin account with email exists, creating it
+	// with password or promoting the existing account. Its email is treated as verified.
+	EnsureAdmin(ctx context.Context, email, password string) (User, error)
 }
 
 // service is a concrete implementation of Service.
 type service struct {
-	repo Repository
+	repo       Repository
+	bcryptCost int
+	lockouts   LockoutStore
+	policy     LockoutPolicy
+	twoFactors TwoFactorStore
+}
+
+// NewService creates a new Service that hashes passwords with the given bcrypt
+// cost and locks out failed logins according to policy. If lockouts is nil,
+// failed logins are not tracked.
+func NewService(r Repository, bcryptCost int, lockouts LockoutStore, policy LockoutPolicy, twoFactors TwoFactorStore) Service {
+	return &service{repo: r, bcryptCost: bcryptCost, lockouts: lockouts, policy: policy, twoFactors: twoFactors}
 }
 
-// NewService creates a new Service.
-func NewService(r Repository) Service {
-	return &service{repo: r}
+func (s *service) List(ctx context.Context, q ListQuery) (Page, error) {
+	if err := q.Normalize(); err != nil {
+		return Page{}, err
+	}
+	return s.repo.List(ctx, q)
 }
 
-func (s *service) GetAll() []User {
-	return s.repo.GetAll()
+func (s *service) GetByID(ctx context.Context, id int) (User, error) {
+	return s.repo.GetByID(ctx, id)
 }
 
-func (s *service) GetByID(id int) (User, bool) {
-	return s.repo.GetByID(id)
+func (s *service) GetByEmail(ctx context.Context, email string) (User, error) {
+	return s.repo.GetByEmail(ctx, normalizeEmail(email))
 }
 
-func (s *service) GetByEmail(email string) (User, bool) {
-	return s.repo.GetByEmail(email)
+func (s *service) Create(ctx context.Context, user User) (User, error) {
+	user.Email = normalizeEmail(user.Email)
+	if err := user.Validate(); err != nil {
+		return User{}, err
+	}
+	if err := s.ensureEmailAvailable(ctx, user.Email, 0); err != nil {
+		return User{}, err
+	}
+	if user.Role == "" {
+		user.Role = RoleViewer
+	}
+	user.LastLoginAt, user.LastLoginIP = nil, ""
+	if err := s.hashPassword(&user); err != nil {
+		return User{}, err
+	}
+	created, err := s.repo.Create(ctx, user)
+	if err != nil {
+		return User{}, err
+	}
+	logging.FromContext(ctx).Info("user created", "created_user_id", created.ID, "role", created.Role)
+	return created, nil
+}
+
+func (s *service) Update(ctx context.Context, id int, user User) (User, error) {
+	user.Email = normalizeEmail(user.Email)
+	if err := user.Validate(); err != nil {
+		return User{}, err
+	}
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+	if err := s.ensureEmailAvailable(ctx, user.Email, id); err != nil {
+		return User{}, err
+	}
+	// Keep the current password and role when the update does not set new ones.
+	if user.Role == "" {
+		user.Role = existing.Role
+	}
+	user.LastLoginAt, user.LastLoginIP = existing.LastLoginAt, existing.LastLoginIP
+	// A new email address has to be verified again.
+	user.EmailVerified = existing.EmailVerified && user.Email == existing.Email
+	if user.Password == "" {
+		user.Password = existing.Password
+		user.PasswordChangedAt = existing.PasswordChangedAt
+	} else {
+		if err := s.hashPassword(&user); err != nil {
+			return User{}, err
+		}
+		user.PasswordChangedAt = time.Now()
+	}
+	updated, err := s.repo.Update(ctx, id, user)
+	if err != nil {
+		return User{}, err
+	}
+	logger := logging.FromContext(ctx)
+	if user.Role != existing.Role {
+		logger.Info("user role changed", "updated_user_id", id, "from", existing.Role, "to", user.Role)
+	}
+	if !user.PasswordChangedAt.Equal(existing.PasswordChangedAt) {
+		logger.Info("user password changed", "updated_user_id", id)
+	}
+	return updated, nil
 }
 
-func (s *service) Create(user User) User {
-	if user.Password != "" {
-		hashed, _ := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-		user.Password = string(hashed)
+func (s *service) UpdateProfile(ctx context.Context, id int, user User, currentPassword string) (User, error) {
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+	if user.Password == "" && normalizeEmail(user.Email) == existing.Email {
+		return s.Update(ctx, id, user)
+	}
+	if currentPassword == "" {
+		return User{}, validation.Errors{{Field: "current_password", Reason: "is required to change the email or password"}}
+	}
+	now := time.Now()
+	if err := s.checkLockout(ctx, existing.Email, "", now); err != nil {
+		return User{}, err
 	}
-	return s.repo.Create(user)
+	if bcrypt.CompareHashAndPassword([]byte(existing.Password), []byte(currentPassword)) != nil {
+		if err := s.recordFailure(ctx, existing.Email, "", now); err != nil {
+			return User{}, err
+		}
+		return User{}, validation.Errors{{Field: "current_password", Reason: "is incorrect"}}
+	}
+	return s.Update(ctx, id, user)
 }
 
-func (s *service) Update(id int, user User) (User, bool) {
-	if user.Password != "" {
-		hashed, _ := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-		user.Password = string(hashed)
+func (s *service) Delete(ctx context.Context, id int) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
 	}
-	return s.repo.Update(id, user)
+	logging.FromContext(ctx).Info("user deleted", "deleted_user_id", id)
+	return nil
 }
 
-func (s *service) Delete(id int) bool {
-	return s.repo.Delete(id)
+func (s *service) Authenticate(ctx context.Context, email, password, ip string) (User, error) {
+	email = normalizeEmail(email)
+	now := time.Now()
+	if err := s.checkLockout(ctx, email, ip, now); err != nil {
+		return User{}, err
+	}
+	user, err := s.repo.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return User{}, err
+	}
+	if err != nil || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		if err := s.recordFailure(ctx, email, ip, now); err != nil {
+			return User{}, err
+		}
+		return User{}, ErrInvalidCredentials
+	}
+	// Keep the account's failures until the second factor is checked as well,
+	// or a known password would reset the count between guessed codes.
+	enabled, err := s.TwoFactorEnabled(ctx, user.ID)
+	if err != nil || enabled {
+		return user, err
+	}
+	return s.completeLogin(ctx, user, ip, now)
 }
 
-func (s *service) Authenticate(email, password string) (User, bool) {
-	user, ok := s.repo.GetByEmail(email)
+func (s *service) AuthenticateSecondFactor(ctx context.Context, id int, code, ip string) (User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+	now := time.Now()
+	if err := s.checkLockout(ctx, user.Email, ip, now); err != nil {
+		return User{}, err
+	}
+	tf, err := s.twoFactors.Get(ctx, id)
+	if errors.Is(err, ErrTwoFactorNotFound) || (err == nil && !tf.Enabled) {
+		return User{}, ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return User{}, err
+	}
+	ok, err := s.useCode(ctx, tf, code, now)
+	if err != nil {
+		return User{}, err
+	}
+	if !ok {
+		if err := s.recordFailure(ctx, user.Email, ip, now); err != nil {
+			return User{}, err
+		}
+		return User{}, ErrInvalidCode
+	}
+	return s.completeLogin(ctx, user, ip, now)
+}
+
+// completeLogin clears the failed logins of user's account and records the login from ip.
+func (s *service) completeLogin(ctx context.Context, user User, ip string, now time.Time) (User, error) {
+	if s.lockouts != nil {
+		if err := s.lockouts.Delete(ctx, LockoutAccount, user.Email); err != nil && !errors.Is(err, ErrLockoutNotFound) {
+			return User{}, err
+		}
+	}
+	if err := s.repo.RecordLogin(ctx, user.ID, now, ip); err != nil {
+		return User{}, err
+	}
+	user.LastLoginAt, user.LastLoginIP = &now, ip
+	return user, nil
+}
+
+// totpSkew is how many 30 second steps a TOTP code may be off by, allowing
+// for clock drift and codes typed just as they change.
+const totpSkew = 1
+
+// errIncorrectCode is returned when a code given to manage two-factor authentication is wrong.
+var errIncorrectCode = validation.Errors{{Field: "code", Reason: "is incorrect or expired"}}
+
+// useCode reports whether code is a valid TOTP code or an unused recovery
+// code of tf at now, using it up if so.
+func (s *service) useCode(ctx context.Context, tf TwoFactor, code string, now time.Time) (bool, error) {
+	code = normalizeCode(code)
+	if step, ok := totp.Validate(tf.Secret, code, now, totpSkew); ok {
+		return s.twoFactors.UseStep(ctx, tf.UserID, step)
+	}
+	used, err := s.twoFactors.UseRecoveryCode(ctx, tf.UserID, hashRecoveryCode(code))
+	if err != nil || !used {
+		return false, err
+	}
+	logging.FromContext(ctx).Warn("recovery code used", "recovery_user_id", tf.UserID, "remaining", len(tf.RecoveryCodes)-1)
+	return true, nil
+}
+
+func (s *service) TwoFactorEnabled(ctx context.Context, id int) (bool, error) {
+	tf, err := s.twoFactors.Get(ctx, id)
+	if errors.Is(err, ErrTwoFactorNotFound) {
+		return false, nil
+	}
+	return tf.Enabled, err
+}
+
+func (s *service) EnrollTOTP(ctx context.Context, id int) ([]byte, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	enabled, err := s.TwoFactorEnabled(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return nil, fmt.Errorf("generate totp secret: %w", err)
+	}
+	if err := s.twoFactors.Save(ctx, TwoFactor{UserID: id, Secret: secret}); err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("totp enrolment started", "totp_user_id", id)
+	return secret, nil
+}
+
+func (s *service) ConfirmTOTP(ctx context.Context, id int, code string) ([]string, error) {
+	tf, err := s.twoFactors.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	step, ok := totp.Validate(tf.Secret, normalizeCode(code), time.Now(), totpSkew)
 	if !ok {
-		return User{}, false
+		return nil, errIncorrectCode
+	}
+	codes, hashes, err := newRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		return nil, fmt.Errorf("generate recovery codes: %w", err)
+	}
+	tf.Enabled, tf.LastStep, tf.RecoveryCodes = true, step, hashes
+	if err := s.twoFactors.Save(ctx, tf); err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("two-factor authentication enabled", "totp_user_id", id)
+	return codes, nil
+}
+
+func (s *service) DisableTOTP(ctx context.Context, id int, code string) error {
+	tf, err := s.twoFactors.Get(ctx, id)
+	if errors.Is(err, ErrTwoFactorNotFound) || (err == nil && !tf.Enabled) {
+		return ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	ok, err := s.useCode(ctx, tf, code, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errIncorrectCode
+	}
+	if err := s.twoFactors.Delete(ctx, id); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("two-factor authentication disabled", "totp_user_id", id)
+	return nil
+}
+
+// lockoutSubjects returns what a login for email from ip counts against.
+// Attempts without a client IP only count against the account.
+func lockoutSubjects(email, ip string) []Lockout {
+	subjects := []Lockout{{Kind: LockoutAccount, Key: email}}
+	if ip != "" {
+		subjects = append(subjects, Lockout{Kind: LockoutIP, Key: ip})
+	}
+	return subjects
+}
+
+// checkLockout returns a *LockedError if logins for email or from ip are locked at now.
+func (s *service) checkLockout(ctx context.Context, email, ip string, now time.Time) error {
+	if s.lockouts == nil {
+		return nil
+	}
+	var until time.Time
+	for _, subject := range lockoutSubjects(email, ip) {
+		l, err := s.lockouts.Get(ctx, subject.Kind, subject.Key)
+		if err != nil {
+			return err
+		}
+		if l.Locked(now) && l.LockedUntil.After(until) {
+			until = *l.LockedUntil
+		}
+	}
+	if until.IsZero() {
+		return nil
+	}
+	return &LockedError{RetryAfter: until.Sub(now)}
+}
+
+// recordFailure counts a failed login for email from ip.
+func (s *service) recordFailure(ctx context.Context, email, ip string, now time.Time) error {
+	if s.lockouts == nil {
+		return nil
+	}
+	for _, subject := range lockoutSubjects(email, ip) {
+		l, err := s.lockouts.Fail(ctx, subject.Kind, subject.Key, s.policy, now)
+		if err != nil {
+			return err
+		}
+		if l.Locked(now) {
+			logging.FromContext(ctx).Warn("login locked out",
+				"lockout_kind", l.Kind, "lockout_key", l.Key, "failures", l.Failures, "locked_until", *l.LockedUntil)
+		}
+	}
+	return nil
+}
+
+func (s *service) Lockouts(ctx context.Context) ([]Lockout, error) {
+	if s.lockouts == nil {
+		return []Lockout{}, nil
+	}
+	all, err := s.lockouts.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	lockouts := make([]Lockout, 0, len(all))
+	for _, l := range all {
+		if !s.policy.stale(l, now) {
+			lockouts = append(lockouts, l)
+		}
+	}
+	sort.Slice(lockouts, func(i, j int) bool { return lockouts[i].LastFailureAt.After(lockouts[j].LastFailureAt) })
+	return lockouts, nil
+}
+
+func (s *service) ClearLockout(ctx context.Context, kind LockoutKind, key string) error {
+	if !kind.Valid() {
+		return validation.Errors{{Field: "kind", Reason: "must be one of account or ip"}}
+	}
+	if kind == LockoutAccount {
+		key = normalizeEmail(key)
+	}
+	if s.lockouts == nil {
+		return ErrLockoutNotFound
+	}
+	if err := s.lockouts.Delete(ctx, kind, key); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("lockout cleared", "lockout_kind", kind, "lockout_key", key)
+	return nil
+}
+
+func (s *service) VerifyEmail(ctx context.Context, id int, email string) (User, error) {
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+	if existing.Email != normalizeEmail(email) {
+		return User{}, ErrEmailChanged
+	}
+	if existing.EmailVerified {
+		return existing, nil
+	}
+	existing.EmailVerified = true
+	verified, err := s.repo.Update(ctx, id, existing)
+	if err != nil {
+		return User{}, err
+	}
+	logging.FromContext(ctx).Info("user email verified", "verified_user_id", id)
+	return verified, nil
+}
+
+func (s *service) EnsureAdmin(ctx context.Context, email, password string) (User, error) {
+	existing, err := s.repo.GetByEmail(ctx, normalizeEmail(email))
+	if errors.Is(err, ErrNotFound) {
+		if password == "" {
+			return User{}, validation.Errors{{Field: "password", Reason: "is required to create the admin account"}}
+		}
+		return s.Create(ctx, User{Name: "Administrator", Email: email, Password: password, Role: RoleAdmin, EmailVerified: true})
+	}
+	if err != nil {
+		return User{}, err
+	}
+	if existing.Role == RoleAdmin && existing.EmailVerified {
+		return existing, nil
+	}
+	previous := existing.Role
+	existing.Role = RoleAdmin
+	existing.EmailVerified = true
+	promoted, err := s.repo.Update(ctx, existing.ID, existing)
+	if err != nil {
+		return User{}, err
+	}
+	if previous != RoleAdmin {
+		logging.FromContext(ctx).Info("user role changed", "updated_user_id", existing.ID, "from", previous, "to", RoleAdmin)
+	}
+	return promoted, nil
+}
+
+// ensureEmailAvailable returns ErrEmailTaken if a user other than id already has email.
+// The repositories enforce the same rule; this check fails fast before hashing.
+func (s *service) ensureEmailAvailable(ctx context.Context, email string, id int) error {
+	existing, err := s.repo.GetByEmail(ctx, email)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != id {
+		return ErrEmailTaken
+	}
+	return nil
+}
+
+// normalizeEmail returns the canonical form of email used for storage and lookups.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// normalizeCode removes the spaces users type to group the digits of a code.
+func normalizeCode(code string) string {
+	return strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+}
+
+// hashPassword replaces a plaintext password on user with its bcrypt hash.
+func (s *service) hashPassword(user *User) error {
+	if user.Password == "" {
+		return nil
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return User{}, false
+	hashed, err := bcrypt.GenerateFromPassword([]byte(user.Password), s.bcryptCost)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
 	}
-	return user, true
+	user.Password = string(hashed)
+	return nil
 }
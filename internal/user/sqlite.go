@@ -0,0 +1,174 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"test-backend/internal/database"
+)
+
+// SQLiteRepository is a SQLite implementation of Repository.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLite repository.
+// The schema is managed by the migrations package.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// userColumns lists the columns read by scanUser, in order.
+const userColumns = "id, name, email, password, role, email_verified, password_changed_at, last_login_at, last_login_ip"
+
+// scanUser reads a row selected with userColumns.
+func scanUser(row interface{ Scan(...any) error }) (User, error) {
+	var u User
+	var passwordChangedAt, lastLoginAt sql.NullTime
+	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.Password, &u.Role, &u.EmailVerified, &passwordChangedAt, &lastLoginAt, &u.LastLoginIP)
+	u.PasswordChangedAt = passwordChangedAt.Time
+	if lastLoginAt.Valid {
+		u.LastLoginAt = &lastLoginAt.Time
+	}
+	return u, err
+}
+
+// nullTime stores the zero time as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+// likeEscaper escapes LIKE wildcards so user input matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *SQLiteRepository) List(ctx context.Context, q ListQuery) (Page, error) {
+	where := ""
+	var args []any
+	if q.Search != "" {
+		prefix := likeEscaper.Replace(q.Search) + "%"
+		where = ` WHERE (name LIKE ? ESCAPE '\' OR email LIKE ? ESCAPE '\')`
+		args = append(args, prefix, prefix)
+	}
+
+	page := Page{Items: []User{}, Limit: q.Limit, Offset: q.Offset}
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users"+where, args...).Scan(&page.Total); err != nil {
+		return Page{}, err
+	}
+
+	dir := "ASC"
+	if q.Desc {
+		dir = "DESC"
+	}
+	order := fmt.Sprintf(" ORDER BY id %s", dir)
+	if q.Sort == SortByName {
+		order = fmt.Sprintf(" ORDER BY name %s, id %s", dir, dir)
+	}
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+userColumns+" FROM users"+where+order+" LIMIT ? OFFSET ?",
+		append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return Page{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return Page{}, err
+		}
+		page.Items = append(page.Items, u)
+	}
+	return page, rows.Err()
+}
+
+func (r *SQLiteRepository) GetByID(ctx context.Context, id int) (User, error) {
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id)
+}
+
+func (r *SQLiteRepository) GetByEmail(ctx context.Context, email string) (User, error) {
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users WHERE email = ? COLLATE NOCASE`, email)
+}
+
+func (r *SQLiteRepository) getOne(ctx context.Context, query string, arg any) (User, error) {
+	u, err := scanUser(r.db.QueryRowContext(ctx, query, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+	return u, nil
+}
+
+func (r *SQLiteRepository) Create(ctx context.Context, user User) (User, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO users (name, email, password, role, email_verified, password_changed_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		user.Name, user.Email, user.Password, user.Role, user.EmailVerified, nullTime(user.PasswordChangedAt))
+	if database.IsUniqueViolation(err) {
+		return User{}, ErrEmailTaken
+	}
+	if err != nil {
+		return User{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return User{}, err
+	}
+	user.ID = int(id)
+	return user, nil
+}
+
+func (r *SQLiteRepository) Update(ctx context.Context, id int, user User) (User, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET name = ?, email = ?, password = ?, role = ?, email_verified = ?, password_changed_at = ? WHERE id = ?`,
+		user.Name, user.Email, user.Password, user.Role, user.EmailVerified, nullTime(user.PasswordChangedAt), id)
+	if database.IsUniqueViolation(err) {
+		return User{}, ErrEmailTaken
+	}
+	if err != nil {
+		return User{}, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return User{}, err
+	} else if n == 0 {
+		return User{}, ErrNotFound
+	}
+	user.ID = id
+	return user, nil
+}
+
+func (r *SQLiteRepository) Delete(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) RecordLogin(ctx context.Context, id int, at time.Time, ip string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET last_login_at = ?, last_login_ip = ? WHERE id = ?`, at.UTC(), ip, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) Ping(ctx context.Context) error {
+	var one int
+	err := r.db.QueryRowContext(ctx, `SELECT 1 FROM users LIMIT 1`).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
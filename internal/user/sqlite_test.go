@@ -0,0 +1,140 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"test-backend/internal/database/dbtest"
+)
+
+func TestSQLiteRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSQLiteRepository(dbtest.Open(t))
+
+	changed := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	created, err := repo.Create(ctx, User{Name: "Alice", Email: "alice@example.com", Password: "hash", Role: RoleEditor, PasswordChangedAt: changed})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.ID == 0 {
+		t.Fatalf("Create: got ID 0")
+	}
+
+	got, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Name != "Alice" || got.Email != "alice@example.com" || got.Password != "hash" || got.Role != RoleEditor ||
+		!got.PasswordChangedAt.Equal(changed) || got.LastLoginAt != nil {
+		t.Errorf("GetByID = %+v, want the created user", got)
+	}
+	if got, err := repo.GetByEmail(ctx, "ALICE@example.com"); err != nil || got.ID != created.ID {
+		t.Errorf("GetByEmail with different case = %+v, %v, want user %d", got, err, created.ID)
+	}
+
+	updated, err := repo.Update(ctx, created.ID, User{Name: "Alicia", Email: "alicia@example.com", Password: "hash", Role: RoleAdmin, EmailVerified: true})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.ID != created.ID {
+		t.Errorf("Update: ID = %d, want %d", updated.ID, created.ID)
+	}
+	if got, _ := repo.GetByID(ctx, created.ID); got.Name != "Alicia" || got.Role != RoleAdmin || !got.EmailVerified || !got.PasswordChangedAt.IsZero() {
+		t.Errorf("GetByID after Update = %+v", got)
+	}
+
+	login := time.Date(2026, 1, 2, 8, 30, 0, 0, time.UTC)
+	if err := repo.RecordLogin(ctx, created.ID, login, "192.0.2.1"); err != nil {
+		t.Fatalf("RecordLogin: %v", err)
+	}
+	if got, _ := repo.GetByID(ctx, created.ID); got.LastLoginAt == nil || !got.LastLoginAt.Equal(login) || got.LastLoginIP != "192.0.2.1" {
+		t.Errorf("GetByID after RecordLogin: last login %v from %q", got.LastLoginAt, got.LastLoginIP)
+	}
+
+	if _, err := repo.Create(ctx, User{Name: "Bob", Email: "bob@example.com"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	page, err := repo.List(ctx, ListQuery{Limit: 10, Sort: SortByName})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if page.Total != 2 || len(page.Items) != 2 || page.Items[0].Name != "Alicia" || page.Items[1].Name != "Bob" {
+		t.Errorf("List = %+v, want Alicia and Bob", page)
+	}
+	page, err = repo.List(ctx, ListQuery{Limit: 10, Search: "bob"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if page.Total != 1 || len(page.Items) != 1 || page.Items[0].Name != "Bob" {
+		t.Errorf("List with search = %+v, want Bob", page)
+	}
+
+	if err := repo.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSQLiteRepositoryErrors(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSQLiteRepository(dbtest.Open(t))
+	alice, err := repo.Create(ctx, User{Name: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	bob, err := repo.Create(ctx, User{Name: "Bob", Email: "bob@example.com"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	_, getErr := repo.GetByID(ctx, 999)
+	_, getEmailErr := repo.GetByEmail(ctx, "nobody@example.com")
+	_, createErr := repo.Create(ctx, User{Email: "alice@example.com"})
+	_, createCaseErr := repo.Create(ctx, User{Email: "Alice@Example.COM"})
+	_, updateTakenErr := repo.Update(ctx, bob.ID, User{Email: "ALICE@example.com"})
+	_, updateOwnErr := repo.Update(ctx, alice.ID, User{Name: "Alicia", Email: "alice@example.com"})
+	_, updateErr := repo.Update(ctx, 999, User{Email: "carol@example.com"})
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"GetByID unknown", getErr, ErrNotFound},
+		{"GetByEmail unknown", getEmailErr, ErrNotFound},
+		{"Create duplicate email", createErr, ErrEmailTaken},
+		{"Create duplicate email in other case", createCaseErr, ErrEmailTaken},
+		{"Update to taken email", updateTakenErr, ErrEmailTaken},
+		{"Update keeping own email", updateOwnErr, nil},
+		{"Update unknown", updateErr, ErrNotFound},
+		{"Delete unknown", repo.Delete(ctx, 999), ErrNotFound},
+		{"RecordLogin unknown", repo.RecordLogin(ctx, 999, time.Now(), "192.0.2.1"), ErrNotFound},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, tt.err, tt.want)
+		}
+	}
+}
+
+func TestSQLiteRepositoryPing(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	repo := NewSQLiteRepository(db)
+	if err := repo.Ping(ctx); err != nil {
+		t.Fatalf("Ping on an empty table: %v", err)
+	}
+	if _, err := repo.Create(ctx, User{Email: "alice@example.com"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repo.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	db.Close()
+	if err := repo.Ping(ctx); err == nil {
+		t.Fatalf("Ping on a closed database: got nil error")
+	}
+}
@@ -0,0 +1,149 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"test-backend/internal/apperr"
+)
+
+// Errors returned by the two-factor methods of Service.
+var (
+	ErrTwoFactorNotFound   = apperr.New(apperr.ErrNotFound, "two-factor authentication is not set up")
+	ErrTwoFactorEnabled    = apperr.New(apperr.ErrConflict, "two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled = apperr.New(apperr.ErrConflict, "two-factor authentication is not enabled")
+	ErrInvalidCode         = apperr.New(apperr.ErrUnauthorized, "invalid two-factor authentication code")
+)
+
+// RecoveryCodeCount is the number of recovery codes issued when two-factor
+// authentication is enabled.
+const RecoveryCodeCount = 10
+
+// TwoFactor is the TOTP second factor of a user.
+type TwoFactor struct {
+	UserID int
+	Secret []byte
+	// Enabled is set once the user has confirmed a code generated from Secret.
+	// Until then the secret is only a pending enrolment.
+	Enabled bool
+	// LastStep is the time step of the last accepted code. Codes from it or
+	// earlier steps are refused, so every code works once.
+	LastStep int64
+	// RecoveryCodes holds hashes of the unused recovery codes.
+	RecoveryCodes []string
+}
+
+// TwoFactorStore persists second factors.
+type TwoFactorStore interface {
+	// Get returns the second factor of userID, or ErrTwoFactorNotFound.
+	Get(ctx context.Context, userID int) (TwoFactor, error)
+	// Save creates or replaces the second factor of tf.UserID.
+	Save(ctx context.Context, tf TwoFactor) error
+	// Delete removes the second factor of userID. It returns ErrTwoFactorNotFound if there is none.
+	Delete(ctx context.Context, userID int) error
+	// UseStep records that a code from step was accepted. It reports false,
+	// recording nothing, if a code from step or a later one was accepted before.
+	UseStep(ctx context.Context, userID int, step int64) (bool, error)
+	// UseRecoveryCode removes the recovery code with hash. It reports false if
+	// the user has no such code.
+	UseRecoveryCode(ctx context.Context, userID int, hash string) (bool, error)
+}
+
+// InMemoryTwoFactorStore is an in-memory implementation of TwoFactorStore.
+// It is safe for concurrent use.
+type InMemoryTwoFactorStore struct {
+	mu      sync.Mutex
+	factors map[int]TwoFactor
+}
+
+// NewInMemoryTwoFactorStore creates a new in-memory two-factor store.
+func NewInMemoryTwoFactorStore() *InMemoryTwoFactorStore {
+	return &InMemoryTwoFactorStore{factors: make(map[int]TwoFactor)}
+}
+
+func (s *InMemoryTwoFactorStore) Get(ctx context.Context, userID int) (TwoFactor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tf, ok := s.factors[userID]
+	if !ok {
+		return TwoFactor{}, ErrTwoFactorNotFound
+	}
+	tf.RecoveryCodes = append([]string(nil), tf.RecoveryCodes...)
+	return tf, nil
+}
+
+func (s *InMemoryTwoFactorStore) Save(ctx context.Context, tf TwoFactor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tf.RecoveryCodes = append([]string(nil), tf.RecoveryCodes...)
+	s.factors[tf.UserID] = tf
+	return nil
+}
+
+func (s *InMemoryTwoFactorStore) Delete(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.factors[userID]; !ok {
+		return ErrTwoFactorNotFound
+	}
+	delete(s.factors, userID)
+	return nil
+}
+
+func (s *InMemoryTwoFactorStore) UseStep(ctx context.Context, userID int, step int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tf, ok := s.factors[userID]
+	if !ok || step <= tf.LastStep {
+		return false, nil
+	}
+	tf.LastStep = step
+	s.factors[userID] = tf
+	return true, nil
+}
+
+func (s *InMemoryTwoFactorStore) UseRecoveryCode(ctx context.Context, userID int, hash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tf := s.factors[userID]
+	for i, h := range tf.RecoveryCodes {
+		if h == hash {
+			tf.RecoveryCodes = append(tf.RecoveryCodes[:i:i], tf.RecoveryCodes[i+1:]...)
+			s.factors[userID] = tf
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// recoveryEncoding writes recovery codes in lower case base32, which avoids
+// digits and letters that are easily confused.
+var recoveryEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns n random recovery codes and their hashes. Each
+// code carries 80 bits, so a leaked hash cannot be reversed by brute force.
+func newRecoveryCodes(n int) (codes, hashes []string, err error) {
+	for i := 0; i < n; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := recoveryEncoding.EncodeToString(b)
+		codes = append(codes, raw[0:4]+"-"+raw[4:8]+"-"+raw[8:12]+"-"+raw[12:16])
+		hashes = append(hashes, hashRecoveryCode(raw))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode returns the stored form of a recovery code, ignoring case,
+// spaces and dashes in what the user typed.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,100 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// SQLiteTwoFactorStore is a SQLite implementation of TwoFactorStore.
+type SQLiteTwoFactorStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteTwoFactorStore creates a new SQLite two-factor store.
+// The schema is managed by the migrations package.
+func NewSQLiteTwoFactorStore(db *sql.DB) *SQLiteTwoFactorStore {
+	return &SQLiteTwoFactorStore{db: db}
+}
+
+func (s *SQLiteTwoFactorStore) Get(ctx context.Context, userID int) (TwoFactor, error) {
+	tf := TwoFactor{UserID: userID}
+	err := s.db.QueryRowContext(ctx, `SELECT secret, enabled, last_step FROM two_factor WHERE user_id = ?`, userID).
+		Scan(&tf.Secret, &tf.Enabled, &tf.LastStep)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TwoFactor{}, ErrTwoFactorNotFound
+	}
+	if err != nil {
+		return TwoFactor{}, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT hash FROM recovery_codes WHERE user_id = ?`, userID)
+	if err != nil {
+		return TwoFactor{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return TwoFactor{}, err
+		}
+		tf.RecoveryCodes = append(tf.RecoveryCodes, hash)
+	}
+	return tf, rows.Err()
+}
+
+func (s *SQLiteTwoFactorStore) Save(ctx context.Context, tf TwoFactor) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO two_factor (user_id, secret, enabled, last_step) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret,
+			enabled = excluded.enabled, last_step = excluded.last_step`,
+		tf.UserID, tf.Secret, tf.Enabled, tf.LastStep)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = ?`, tf.UserID); err != nil {
+		return err
+	}
+	for _, hash := range tf.RecoveryCodes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO recovery_codes (user_id, hash) VALUES (?, ?)`, tf.UserID, hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteTwoFactorStore) Delete(ctx context.Context, userID int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM two_factor WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTwoFactorNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteTwoFactorStore) UseStep(ctx context.Context, userID int, step int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE two_factor SET last_step = ? WHERE user_id = ? AND last_step < ?`, step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *SQLiteTwoFactorStore) UseRecoveryCode(ctx context.Context, userID int, hash string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = ? AND hash = ?`, userID, hash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
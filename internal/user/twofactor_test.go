@@ -0,0 +1,104 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"test-backend/internal/totp"
+)
+
+const testPassword = "correct horse 1"
+
+// newTwoFactorUser returns a service that locks accounts after three failures,
+// a user of it with two-factor authentication enabled, their TOTP secret, the
+// TOTP step whose code confirmed the enrolment and their recovery codes.
+func newTwoFactorUser(t *testing.T) (Service, User, []byte, int64, []string) {
+	t.Helper()
+	ctx := context.Background()
+	policy := LockoutPolicy{Threshold: 3, IPThreshold: 10, BaseDuration: time.Minute, MaxDuration: time.Hour, ResetAfter: time.Hour}
+	s := NewService(NewInMemoryRepository(), bcrypt.MinCost, NewInMemoryLockoutStore(), policy, NewInMemoryTwoFactorStore())
+	u, err := s.Create(ctx, User{Name: "Alice", Email: "alice@example.com", Password: testPassword})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	secret, err := s.EnrollTOTP(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP: %v", err)
+	}
+	step := totp.Step(time.Now())
+	codes, err := s.ConfirmTOTP(ctx, u.ID, totp.Code(secret, step))
+	if err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+	return s, u, secret, step, codes
+}
+
+func TestAuthenticateSecondFactorRejectsReplayedCodes(t *testing.T) {
+	ctx := context.Background()
+	s, u, secret, step, _ := newTwoFactorUser(t)
+
+	// The code used to confirm enrolment is spent; the next one, within the
+	// allowed skew, works once.
+	tests := []struct {
+		name string
+		code string
+		ok   bool
+	}{
+		{"code used for confirmation", totp.Code(secret, step), false},
+		{"fresh code", totp.Code(secret, step+1), true},
+		{"replayed code", totp.Code(secret, step+1), false},
+	}
+	for _, tt := range tests {
+		_, err := s.AuthenticateSecondFactor(ctx, u.ID, tt.code, "192.0.2.1")
+		if tt.ok && err != nil {
+			t.Errorf("%s: AuthenticateSecondFactor: %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("%s: err = %v, want ErrInvalidCode", tt.name, err)
+		}
+	}
+}
+
+func TestAuthenticateSecondFactorRecoveryCodeWorksOnce(t *testing.T) {
+	ctx := context.Background()
+	s, u, _, _, codes := newTwoFactorUser(t)
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), RecoveryCodeCount)
+	}
+
+	if _, err := s.AuthenticateSecondFactor(ctx, u.ID, codes[0], "192.0.2.1"); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if _, err := s.AuthenticateSecondFactor(ctx, u.ID, codes[0], "192.0.2.1"); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("second use: err = %v, want ErrInvalidCode", err)
+	}
+	if _, err := s.AuthenticateSecondFactor(ctx, u.ID, codes[1], "192.0.2.1"); err != nil {
+		t.Fatalf("other code: %v", err)
+	}
+}
+
+func TestAuthenticateSecondFactorFailuresLockAccount(t *testing.T) {
+	ctx := context.Background()
+	s, u, secret, step, _ := newTwoFactorUser(t)
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.AuthenticateSecondFactor(ctx, u.ID, "wrong", "192.0.2.1"); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("failure %d: err = %v, want ErrInvalidCode", i+1, err)
+		}
+	}
+
+	// The account is locked for both factors, even with correct credentials
+	// and from another IP.
+	var locked *LockedError
+	code := totp.Code(secret, step+1)
+	if _, err := s.AuthenticateSecondFactor(ctx, u.ID, code, "198.51.100.1"); !errors.As(err, &locked) {
+		t.Errorf("AuthenticateSecondFactor: err = %v, want *LockedError", err)
+	}
+	if _, err := s.Authenticate(ctx, u.Email, testPassword, "198.51.100.1"); !errors.As(err, &locked) {
+		t.Errorf("Authenticate: err = %v, want *LockedError", err)
+	}
+}
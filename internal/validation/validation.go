@@ -0,0 +1,145 @@
+// Package validation checks request and domain values and reports failures per field.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"test-backend/internal/apperr"
+)
+
+// Password policy limits. bcrypt ignores everything after 72 bytes.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// FieldError describes why a single field failed validation.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// Errors lists every field that failed validation. It wraps apperr.ErrInvalid.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + ": " + fe.Reason
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+func (e Errors) Unwrap() error { return apperr.ErrInvalid }
+
+// Validator accumulates field errors. The zero value is ready to use.
+type Validator struct {
+	errs Errors
+}
+
+// Add records a failure for field.
+func (v *Validator) Add(field, reason string) {
+	v.errs = append(v.errs, FieldError{Field: field, Reason: reason})
+}
+
+// Required checks that value is not blank.
+func (v *Validator) Required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, "is required")
+		return false
+	}
+	return true
+}
+
+// MaxLength checks that value has at most n characters.
+func (v *Validator) MaxLength(field, value string, n int) {
+	if len([]rune(value)) > n {
+		v.Add(field, fmt.Sprintf("must be at most %d characters", n))
+	}
+}
+
+// Email checks that value is a bare email address such as "name@example.com".
+func (v *Validator) Email(field, value string) {
+	if !v.Required(field, value) {
+		return
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != strings.TrimSpace(value) {
+		v.Add(field, "must be a valid email address")
+	}
+}
+
+// Password checks value against the password policy: between MinPasswordLength
+// characters and MaxPasswordLength bytes, with at least one letter and one digit.
+func (v *Validator) Password(field, value string) {
+	if !v.Required(field, value) {
+		return
+	}
+	switch {
+	case len([]rune(value)) < MinPasswordLength:
+		v.Add(field, fmt.Sprintf("must be at least %d characters", MinPasswordLength))
+	case len(value) > MaxPasswordLength:
+		v.Add(field, fmt.Sprintf("must be at most %d bytes", MaxPasswordLength))
+	case !strings.ContainsFunc(value, unicode.IsLetter) || !strings.ContainsFunc(value, unicode.IsDigit):
+		v.Add(field, "must contain at least one letter and one digit")
+	}
+}
+
+// Range checks that min <= value <= max.
+func (v *Validator) Range(field string, value, min, max float64) {
+	if value < min || value > max {
+		v.Add(field, fmt.Sprintf("must be between %s and %s", formatNumber(min), formatNumber(max)))
+	}
+}
+
+// Err returns the accumulated errors, or nil if there are none.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// BindError converts an error from decoding a JSON request body into an error
+// that handlers can report: a field error for type mismatches, or a bad request otherwise.
+func BindError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return Errors{{Field: typeErr.Field, Reason: "must be " + jsonTypeName(typeErr.Type.Kind())}}
+	}
+	if errors.Is(err, io.EOF) {
+		return apperr.New(apperr.ErrBadRequest, "request body is required")
+	}
+	return apperr.New(apperr.ErrBadRequest, "malformed JSON request body")
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// jsonTypeName describes the JSON type expected for a Go kind.
+func jsonTypeName(k reflect.Kind) string {
+	switch k {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -1,15 +1,36 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
 	_ "test-backend/docs"
 	"test-backend/internal/auth"
+	"test-backend/internal/config"
+	"test-backend/internal/database"
+	"test-backend/internal/health"
+	"test-backend/internal/logging"
+	"test-backend/internal/mail"
+	"test-backend/internal/metrics"
+	"test-backend/internal/migrations"
+	"test-backend/internal/problem"
 	"test-backend/internal/product"
+	"test-backend/internal/ratelimit"
+	"test-backend/internal/shutdown"
+	"test-backend/internal/tracing"
 	"test-backend/internal/user"
 )
 
@@ -23,41 +44,306 @@ import (
 // @in header
 // @name Authorization
 func main() {
-	repo := user.NewInMemoryRepository()
-	service := user.NewService(repo)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML config file")
+	storage := flag.String("storage", "", "storage backend: memory or sqlite (overrides the config)")
+	dbPath := flag.String("db", "", "path to the SQLite database file (overrides the config)")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("could not load config: %v", err)
+	}
+	if *storage != "" {
+		cfg.Storage.Backend = *storage
+	}
+	if *dbPath != "" {
+		cfg.Storage.DBPath = *dbPath
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	logger, err := logging.New(os.Stdout, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		log.Fatalf("could not set up logging: %v", err)
+	}
+	// Route the standard library logger, and so log.Fatalf, through slog too.
+	slog.SetDefault(logger)
+	if cfg.JWT.Secret == config.DefaultJWTSecret {
+		slog.Warn("using the default JWT secret; set JWT_SECRET before deploying")
+	}
+	gin.SetMode(cfg.GinMode)
+
+	var hooks shutdown.Hooks
+	stopTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("could not set up tracing: %v", err)
+	}
+	hooks.Add("tracing", stopTracing)
+
+	st, err := newStores(cfg.Storage.Backend, cfg.Storage.DBPath, &hooks)
+	if err != nil {
+		log.Fatalf("could not set up storage: %v", err)
+	}
+	m := metrics.New()
+	st.users = tracing.UserRepository(m.UserRepository(st.users))
+	st.products = tracing.ProductRepository(m.ProductRepository(st.products))
+
+	checks := health.NewRegistry(2 * time.Second)
+	checks.Register("users", st.users.Ping)
+	checks.Register("products", st.products.Ping)
+
+	var lockouts user.LockoutStore
+	if cfg.Lockout.Enabled {
+		lockouts = st.lockouts
+	}
+	service := tracing.UserService(user.NewService(st.users, cfg.BcryptCost, lockouts, user.LockoutPolicy{
+		Threshold:    cfg.Lockout.Threshold,
+		IPThreshold:  cfg.Lockout.IPThreshold,
+		BaseDuration: cfg.Lockout.BaseDuration,
+		MaxDuration:  cfg.Lockout.MaxDuration,
+		ResetAfter:   cfg.Lockout.ResetAfter,
+	}, st.twoFactors))
 	handler := user.NewHandler(service)
+	if err := bootstrapAdmin(service); err != nil {
+		log.Fatalf("could not bootstrap admin: %v", err)
+	}
 
-	productRepo := product.NewInMemoryRepository()
-	productService := product.NewService(productRepo)
+	productService := tracing.ProductService(product.NewService(st.products))
 	productHandler := product.NewHandler(productService)
-	jwtKey := []byte("secret")
-	authHandler := auth.NewHandler(service, jwtKey)
+	keys, err := newKeyring(cfg.JWT)
+	if err != nil {
+		log.Fatalf("could not load signing keys: %v", err)
+	}
+	authHandler := auth.NewHandler(service, auth.TokenConfig{
+		Keys:                 keys,
+		AccessTokenTTL:       cfg.JWT.AccessTokenTTL,
+		RefreshTokenTTL:      cfg.JWT.RefreshTokenTTL,
+		PasswordResetTTL:     cfg.PasswordReset.TTL,
+		EmailVerificationTTL: cfg.EmailVerification.TTL,
+		RequireVerifiedEmail: cfg.EmailVerification.Required,
+		ChallengeTTL:         cfg.TwoFactor.ChallengeTTL,
+		TOTPIssuer:           cfg.TwoFactor.Issuer,
+	}, auth.MailConfig{
+		Mailer:           newMailer(cfg.Mail),
+		PasswordResetURL: cfg.PasswordReset.URL,
+		VerificationURL:  cfg.EmailVerification.URL,
+	}, st.refreshTokens, st.revocations, st.passwordResets, m)
+	// Registered after the stores so it runs before they are closed.
+	hooks.Add("mail", authHandler.Wait)
 
-	r := gin.Default()
+	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("invalid trusted proxies: %v", err)
+	}
+	r.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(traced)), logging.RequestID(logger), logging.AccessLog(), m.Middleware(), gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		logging.FromContext(c.Request.Context()).Error("panic recovered", "panic", recovered, "stack", string(debug.Stack()))
+		problem.Abort(c, http.StatusInternalServerError, "")
+	}))
+	r.NoRoute(func(c *gin.Context) {
+		problem.Write(c, problem.New(http.StatusNotFound, "no route matches the request path"))
+	})
 
 	// Swagger docs endpoint
 	r.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	r.POST("/register", authHandler.Register)
-	r.POST("/login", authHandler.Login)
+	r.GET("/metrics", m.Handler())
+	r.GET("/healthz", checks.Liveness)
+	r.GET("/readyz", checks.Readiness)
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
+	limits := newLimiter(cfg.RateLimit, st)
+	authLimit := limits("auth", cfg.RateLimit.Auth, ratelimit.ByIP)
+	loginLimit := limits("login", cfg.RateLimit.Login, ratelimit.ByJSONField("email"))
+	r.POST("/register", authLimit, authHandler.Register)
+	r.POST("/login", authLimit, loginLimit, authHandler.Login)
+	r.POST("/login/2fa", authLimit, authHandler.LoginSecondFactor)
+	r.POST("/token/refresh", authLimit, authHandler.Refresh)
+	r.POST("/password/forgot", authLimit, authHandler.ForgotPassword)
+	r.POST("/password/reset", authLimit, authHandler.ResetPassword)
+	r.GET("/verify-email", authLimit, authHandler.VerifyEmail)
+	r.POST("/verify-email/resend", authLimit, authHandler.ResendVerification)
 
 	authorized := r.Group("/")
-	authorized.Use(auth.JWTMiddleware(jwtKey))
+	authorized.Use(authHandler.JWTMiddleware(), limits("api", cfg.RateLimit.API, ratelimit.ByUser))
 	{
-		authorized.GET("/users", handler.GetUsers)
-		authorized.GET("/users/:id", handler.GetUser)
-		authorized.POST("/users", handler.CreateUser)
-		authorized.PUT("/users/:id", handler.UpdateUser)
-		authorized.DELETE("/users/:id", handler.DeleteUser)
+		authorized.POST("/logout", authHandler.Logout)
+		authorized.POST("/logout-all", authHandler.LogoutAll)
+		authorized.GET("/me", authHandler.GetMe)
+		authorized.PUT("/me", authHandler.UpdateMe)
+		authorized.GET("/2fa", authHandler.GetTwoFactor)
+		authorized.POST("/2fa/totp", authHandler.EnrollTOTP)
+		authorized.POST("/2fa/totp/confirm", authHandler.ConfirmTOTP)
+		authorized.POST("/2fa/totp/disable", authHandler.DisableTOTP)
+
+		admins := authorized.Group("/", auth.RequireRole(user.RoleAdmin))
+		if cfg.TwoFactor.RequiredForAdmins {
+			admins.Use(authHandler.RequireTwoFactor())
+		}
+		admins.GET("/users", handler.GetUsers)
+		admins.GET("/users/:id", handler.GetUser)
+		admins.POST("/users", handler.CreateUser)
+		admins.PUT("/users/:id", handler.UpdateUser)
+		admins.DELETE("/users/:id", handler.DeleteUser)
+		admins.GET("/lockouts", handler.GetLockouts)
+		admins.DELETE("/lockouts/:kind/:key", handler.ClearLockout)
 
 		authorized.GET("/products", productHandler.GetProducts)
 		authorized.GET("/products/:id", productHandler.GetProduct)
-		authorized.POST("/products", productHandler.CreateProduct)
-		authorized.PUT("/products/:id", productHandler.UpdateProduct)
-		authorized.DELETE("/products/:id", productHandler.DeleteProduct)
+
+		editors := authorized.Group("/", auth.RequireRole(user.RoleAdmin, user.RoleEditor))
+		editors.POST("/products", productHandler.CreateProduct)
+		editors.PUT("/products/:id", productHandler.UpdateProduct)
+		editors.DELETE("/products/:id", productHandler.DeleteProduct)
+	}
+
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           r,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
 	}
+	if err := serve(srv, cfg.Server, checks, &hooks); err != nil {
+		log.Fatalf("server: %v", err)
+	}
+}
+
+// traced reports whether a request should be traced. Probes and metric
+// scrapes are skipped so they do not drown out real traffic.
+func traced(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/healthz", "/readyz", "/metrics":
+		return false
+	}
+	return true
+}
+
+// newLimiter returns a function building the rate limiting middleware for a
+// route group, all sharing the configured store. When rate limiting is
+// disabled the middleware lets every request through.
+func newLimiter(cfg config.RateLimit, st stores) func(name string, limit config.Limit, key ratelimit.KeyFunc) gin.HandlerFunc {
+	var store ratelimit.Store = ratelimit.NewInMemoryStore()
+	if cfg.Store == "sqlite" {
+		store = st.rateLimits
+	}
+	return func(name string, limit config.Limit, key ratelimit.KeyFunc) gin.HandlerFunc {
+		if !cfg.Enabled {
+			return func(c *gin.Context) { c.Next() }
+		}
+		return ratelimit.Middleware(store, name, ratelimit.Limit{
+			Requests: limit.Requests,
+			Per:      limit.Per,
+			Burst:    limit.Burst,
+		}, key)
+	}
+}
+
+// newMailer returns the mailer for the configured transport.
+func newMailer(cfg config.Mail) mail.Mailer {
+	switch cfg.Transport {
+	case "smtp":
+		return mail.NewSMTPMailer(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.From)
+	case "file":
+		return mail.NewFileMailer(cfg.File, cfg.From)
+	default:
+		return mail.NewLogMailer()
+	}
+}
+
+// bootstrapAdmin creates or promotes the admin account named by the
+// ADMIN_EMAIL and ADMIN_PASSWORD environment variables, if set.
+func bootstrapAdmin(service user.Service) error {
+	email := os.Getenv("ADMIN_EMAIL")
+	if email == "" {
+		return nil
+	}
+	admin, err := service.EnsureAdmin(context.Background(), email, os.Getenv("ADMIN_PASSWORD"))
+	if err != nil {
+		return err
+	}
+	slog.Info("admin account is ready", "email", admin.Email)
+	return nil
+}
+
+// newKeyring builds the access token keyring from the JWT settings. The HMAC
+// secret, if any, signs tokens until the first configured key activates.
+func newKeyring(cfg config.JWT) (*auth.Keyring, error) {
+	var keys []auth.SigningKey
+	if cfg.Secret != "" {
+		keys = append(keys, auth.NewHMACKey("hs256", []byte(cfg.Secret), time.Time{}))
+	}
+	for _, k := range cfg.Keys {
+		key, err := auth.LoadAsymmetricKey(k.ID, k.File, k.ActiveFrom)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return auth.NewKeyring(cfg.RotationOverlap, keys...)
+}
+
+// stores holds the persistence backends the server is built on.
+type stores struct {
+	users          user.Repository
+	products       product.Repository
+	refreshTokens  auth.RefreshStore
+	revocations    auth.RevocationStore
+	lockouts       user.LockoutStore
+	passwordResets auth.PasswordResetStore
+	twoFactors     user.TwoFactorStore
+	// rateLimits is only set for SQLite, as in-memory rate limiting needs no backend.
+	rateLimits ratelimit.Store
+}
 
-	if err := r.Run(":8080"); err != nil {
-		log.Fatalf("could not run server: %v", err)
+// newStores builds the stores for the selected storage backend and registers
+// hooks to close them on shutdown.
+func newStores(storage, dbPath string, hooks *shutdown.Hooks) (stores, error) {
+	switch storage {
+	case "memory":
+		return stores{
+			users:          user.NewInMemoryRepository(),
+			products:       product.NewInMemoryRepository(),
+			refreshTokens:  auth.NewInMemoryRefreshStore(),
+			revocations:    auth.NewInMemoryRevocationStore(),
+			lockouts:       user.NewInMemoryLockoutStore(),
+			passwordResets: auth.NewInMemoryPasswordResetStore(),
+			twoFactors:     user.NewInMemoryTwoFactorStore(),
+		}, nil
+	case "sqlite":
+		db, err := database.OpenSQLite(dbPath)
+		if err != nil {
+			return stores{}, err
+		}
+		hooks.Add("sqlite", func(context.Context) error { return db.Close() })
+		migrator, err := migrations.New(db)
+		if err != nil {
+			return stores{}, err
+		}
+		ran, err := migrator.Up()
+		if err != nil {
+			return stores{}, err
+		}
+		for _, m := range ran {
+			slog.Info("applied migration", "version", m.Version, "name", m.Name)
+		}
+		return stores{
+			users:          user.NewSQLiteRepository(db),
+			products:       product.NewSQLiteRepository(db),
+			refreshTokens:  auth.NewSQLiteRefreshStore(db),
+			revocations:    auth.NewSQLiteRevocationStore(db),
+			lockouts:       user.NewSQLiteLockoutStore(db),
+			passwordResets: auth.NewSQLitePasswordResetStore(db),
+			twoFactors:     user.NewSQLiteTwoFactorStore(db),
+			rateLimits:     ratelimit.NewSQLiteStore(db),
+		}, nil
+	default:
+		return stores{}, fmt.Errorf("unknown storage backend %q", storage)
 	}
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"test-backend/internal/config"
+	"test-backend/internal/health"
+	"test-backend/internal/shutdown"
+)
+
+// serve runs srv until SIGINT or SIGTERM, then drains it: checks start
+// reporting the server as unready, requests keep being served for
+// cfg.DrainDelay, in-flight requests get until cfg.ShutdownTimeout to finish,
+// and finally hooks run within what is left of that timeout. A second signal
+// stops waiting for the drain delay.
+func serve(srv *http.Server, cfg config.Server, checks *health.Registry, hooks *shutdown.Hooks) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", srv.Addr)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		// The server never started or failed, so there is nothing to drain.
+		hookErr := hooks.Run(context.Background())
+		return errors.Join(err, hookErr)
+	case <-ctx.Done():
+	}
+	stop()
+	slog.Info("shutting down", "drain_delay", cfg.DrainDelay.String())
+	checks.ShutDown()
+
+	if cfg.DrainDelay > 0 {
+		again, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		select {
+		case <-time.After(cfg.DrainDelay):
+		case <-again.Done():
+		}
+		cancel()
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	var errs []error
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := hooks.Run(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	slog.Info("server stopped")
+	return nil
+}